@@ -0,0 +1,373 @@
+package config
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config はサーバーとサブコマンドの設定。Load で読み込み、値は検証済み
+type Config struct {
+	Port          string
+	WebhookSecret string
+	// AdminToken が空なら管理 API は無効
+	AdminToken string
+
+	// GitHubApp はリポジトリを操作するメインApp
+	GitHubApp App
+	// LabelApp はラベル操作App。秘密鍵は APP_PRIVATE_KEY シークレットとして登録する
+	LabelApp App
+	// KeyReloadInterval は秘密鍵ファイルの変更を確認する間隔
+	KeyReloadInterval time.Duration
+
+	Retry Retry
+	// ClientCacheSize はキャッシュするインストールごとの GitHub クライアントの上限。0 なら既定値を使う
+	ClientCacheSize int
+
+	JobStore    string
+	JobStoreDir string
+	JobWorkers  int
+	// JobFailedRetention は再試行を使い切ったジョブを残す期間
+	JobFailedRetention time.Duration
+	ShutdownTimeout    time.Duration
+
+	DeliveryStore      string
+	DeliveryStorePath  string
+	DeliveryTTL        time.Duration
+	DeliveryMaxEntries int
+
+	StatusStore    string
+	StatusStoreDir string
+
+	SetupOnInstall   bool
+	SetupProfilePath string
+	SetupRulesPath   string
+
+	LogLevel       string
+	LogFormat      string
+	TracesExporter string
+}
+
+// App は GitHub App の ID と秘密鍵（PEM）
+type App struct {
+	ID         int64
+	PrivateKey []byte
+	// PrivateKeyFile は秘密鍵をファイルから読み込んだ場合のパス。変更を監視して鍵を入れ替える
+	PrivateKeyFile string
+}
+
+// Retry は GitHub API の再試行方針の上書き。ゼロ値（nil）の項目は既定値を使う
+type Retry struct {
+	MaxAttempts      int
+	InitialBackoff   time.Duration
+	MaxBackoff       time.Duration
+	MaxRateLimitWait time.Duration
+	RetryNotFound    *bool
+}
+
+// keys は設定できる項目（環境変数名）。設定ファイルでは小文字でも書ける
+var keys = []string{
+	"PORT", "WEBHOOK_SECRET", "ADMIN_TOKEN",
+	"GITHUB_APP_ID", "GITHUB_PRIVATE_KEY", "GITHUB_PRIVATE_KEY_FILE",
+	"LABEL_APP_ID", "LABEL_PRIVATE_KEY", "LABEL_PRIVATE_KEY_FILE", "PRIVATE_KEY_RELOAD_INTERVAL",
+	"GITHUB_RETRY_MAX_ATTEMPTS", "GITHUB_RETRY_INITIAL_BACKOFF", "GITHUB_RETRY_MAX_BACKOFF",
+	"GITHUB_RETRY_MAX_RATELIMIT_WAIT", "GITHUB_RETRY_NOT_FOUND",
+	"GITHUB_CLIENT_CACHE_SIZE",
+	"JOB_STORE", "JOB_STORE_DIR", "JOB_WORKERS", "JOB_FAILED_RETENTION", "SHUTDOWN_TIMEOUT",
+	"DELIVERY_STORE", "DELIVERY_STORE_PATH", "DELIVERY_TTL", "DELIVERY_MAX_ENTRIES",
+	"STATUS_STORE", "STATUS_STORE_DIR",
+	"SETUP_ON_INSTALL", "SETUP_PROFILE_PATH", "SETUP_RULES_PATH",
+	"LOG_LEVEL", "LOG_FORMAT", "OTEL_TRACES_EXPORTER",
+}
+
+// LookupFunc は環境変数などから設定値を探す（os.LookupEnv と同じ形）
+type LookupFunc func(key string) (string, bool)
+
+// Load は lookup（通常は .env を読み込んだ後の os.LookupEnv）と設定ファイル path から設定を読み込んで検証する。
+// 同じ項目は lookup の値を優先する。path が空なら設定ファイルは使わない。
+// 全ての項目を検証し、エラーはまとめて返す
+func Load(path string, lookup LookupFunc) (*Config, error) {
+	if path != "" {
+		file, err := loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		lookup = withFallback(lookup, file)
+	}
+
+	l := &loader{lookup: lookup}
+	cfg := &Config{
+		Port:          l.str("PORT", "8080"),
+		WebhookSecret: l.str("WEBHOOK_SECRET", ""),
+		AdminToken:    l.str("ADMIN_TOKEN", ""),
+
+		GitHubApp:         l.app("GITHUB_APP_ID", "GITHUB_PRIVATE_KEY"),
+		LabelApp:          l.app("LABEL_APP_ID", "LABEL_PRIVATE_KEY"),
+		KeyReloadInterval: l.positiveDuration("PRIVATE_KEY_RELOAD_INTERVAL", 30*time.Second),
+
+		Retry: Retry{
+			MaxAttempts:      l.int("GITHUB_RETRY_MAX_ATTEMPTS", 0, 1),
+			InitialBackoff:   l.duration("GITHUB_RETRY_INITIAL_BACKOFF", 0),
+			MaxBackoff:       l.duration("GITHUB_RETRY_MAX_BACKOFF", 0),
+			MaxRateLimitWait: l.duration("GITHUB_RETRY_MAX_RATELIMIT_WAIT", 0),
+			RetryNotFound:    l.optionalBool("GITHUB_RETRY_NOT_FOUND"),
+		},
+		ClientCacheSize: l.int("GITHUB_CLIENT_CACHE_SIZE", 0, 1),
+
+		JobStore:           l.oneOf("JOB_STORE", "file", "file", "memory"),
+		JobStoreDir:        l.str("JOB_STORE_DIR", "data/jobs"),
+		JobWorkers:         l.int("JOB_WORKERS", 2, 1),
+		JobFailedRetention: l.positiveDuration("JOB_FAILED_RETENTION", 7*24*time.Hour),
+		ShutdownTimeout:    l.positiveDuration("SHUTDOWN_TIMEOUT", 30*time.Second),
+
+		DeliveryStore:      l.oneOf("DELIVERY_STORE", "memory", "memory", "file"),
+		DeliveryStorePath:  l.str("DELIVERY_STORE_PATH", "data/deliveries.json"),
+		DeliveryTTL:        l.positiveDuration("DELIVERY_TTL", 72*time.Hour),
+		DeliveryMaxEntries: l.int("DELIVERY_MAX_ENTRIES", 10000, 1),
+
+		StatusStore:    l.oneOf("STATUS_STORE", "file", "file", "memory"),
+		StatusStoreDir: l.str("STATUS_STORE_DIR", "data/runs"),
+
+		SetupOnInstall:   l.bool("SETUP_ON_INSTALL", false),
+		SetupProfilePath: l.str("SETUP_PROFILE_PATH", ""),
+		SetupRulesPath:   l.str("SETUP_RULES_PATH", ""),
+
+		LogLevel:       l.oneOf("LOG_LEVEL", "info", "debug", "info", "warn", "error"),
+		LogFormat:      l.oneOf("LOG_FORMAT", "text", "text", "json"),
+		TracesExporter: l.oneOf("OTEL_TRACES_EXPORTER", "none", "otlp", "console", "stdout", "none"),
+	}
+
+	if err := errors.Join(l.errs...); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// loadFile は設定ファイル（YAML/JSON）を読み込む。キーは環境変数名（大文字小文字は区別しない）
+func loadFile(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	// JSON は YAML として読めるため、数値や真偽値もそのまま文字列として扱える
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+
+	values := make(map[string]string, len(raw))
+	var errs []error
+	for k, v := range raw {
+		key := strings.ToUpper(k)
+		if !slices.Contains(keys, key) {
+			errs = append(errs, fmt.Errorf("%s: unknown key %q", path, k))
+			continue
+		}
+		values[key] = v
+	}
+	if err := errors.Join(errs...); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func withFallback(lookup LookupFunc, file map[string]string) LookupFunc {
+	return func(key string) (string, bool) {
+		if v, ok := lookup(key); ok && v != "" {
+			return v, true
+		}
+		v, ok := file[key]
+		return v, ok
+	}
+}
+
+// loader は値を読み込みながらエラーを溜める
+type loader struct {
+	lookup LookupFunc
+	errs   []error
+}
+
+func (l *loader) get(key string) string {
+	v, _ := l.lookup(key)
+	return strings.TrimSpace(v)
+}
+
+func (l *loader) fail(key string, format string, args ...any) {
+	l.errs = append(l.errs, fmt.Errorf("%s: "+format, append([]any{key}, args...)...))
+}
+
+func (l *loader) str(key, def string) string {
+	if v := l.get(key); v != "" {
+		return v
+	}
+	return def
+}
+
+func (l *loader) oneOf(key, def string, allowed ...string) string {
+	v := strings.ToLower(l.str(key, def))
+	if !slices.Contains(allowed, v) {
+		l.fail(key, "invalid value %q (must be one of %s)", v, strings.Join(allowed, ", "))
+	}
+	return v
+}
+
+func (l *loader) int(key string, def, minValue int) int {
+	v := l.get(key)
+	if v == "" {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < minValue {
+		l.fail(key, "must be an integer >= %d, got %q", minValue, v)
+	}
+	return n
+}
+
+func (l *loader) duration(key string, def time.Duration) time.Duration {
+	v := l.get(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		l.fail(key, "must be a non-negative duration such as 30s, got %q", v)
+	}
+	return d
+}
+
+// positiveDuration は 0 を許さない期間を読み込む（0 で重複排除や終了待ちが黙って無効にならないように）
+func (l *loader) positiveDuration(key string, def time.Duration) time.Duration {
+	v := l.get(key)
+	if v == "" {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		l.fail(key, "must be a positive duration such as 30s, got %q", v)
+	}
+	return d
+}
+
+func (l *loader) bool(key string, def bool) bool {
+	if b := l.optionalBool(key); b != nil {
+		return *b
+	}
+	return def
+}
+
+func (l *loader) optionalBool(key string) *bool {
+	v := l.get(key)
+	if v == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		l.fail(key, "must be true or false, got %q", v)
+		return nil
+	}
+	return &b
+}
+
+// app は App ID と、key または key_FILE の秘密鍵を読み込む
+func (l *loader) app(idKey, key string) App {
+	app := App{ID: l.appID(idKey)}
+
+	fileKey := key + "_FILE"
+	app.PrivateKeyFile = l.get(fileKey)
+	switch {
+	case app.PrivateKeyFile != "" && l.get(key) != "":
+		l.fail(key, "cannot be combined with %s", fileKey)
+	case app.PrivateKeyFile != "":
+		data, err := os.ReadFile(app.PrivateKeyFile)
+		if err != nil {
+			l.fail(fileKey, "%v", err)
+			break
+		}
+		if app.PrivateKey, err = NormalizePrivateKey(string(data)); err != nil {
+			l.fail(fileKey, "%v", err)
+		}
+	default:
+		app.PrivateKey = l.privateKey(key)
+	}
+	return app
+}
+
+func (l *loader) appID(key string) int64 {
+	v := l.get(key)
+	if v == "" {
+		l.fail(key, "is required")
+		return 0
+	}
+	id, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || id <= 0 {
+		l.fail(key, "must be a numeric GitHub App ID, got %q", v)
+	}
+	return id
+}
+
+func (l *loader) privateKey(key string) []byte {
+	v := l.get(key)
+	if v == "" {
+		l.fail(key, "is required (or set %s_FILE)", key)
+		return nil
+	}
+	pemKey, err := NormalizePrivateKey(v)
+	if err != nil {
+		l.fail(key, "%v", err)
+		return nil
+	}
+	return pemKey
+}
+
+// NormalizePrivateKey は PEM（改行を \n で書いたものを含む）または base64 でエンコードした PEM を
+// PEM に揃え、RSA の秘密鍵として読めることを確認する
+func NormalizePrivateKey(value string) ([]byte, error) {
+	text := strings.TrimSpace(value)
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, `\n`, "\n")
+	if !strings.Contains(text, "BEGIN") || !strings.Contains(text, "PRIVATE KEY") {
+		decoded, err := base64.StdEncoding.DecodeString(text)
+		if err != nil {
+			return nil, errors.New("must be PEM or base64 encoded PEM content")
+		}
+		text = strings.TrimSpace(string(decoded))
+		text = strings.ReplaceAll(text, "\r\n", "\n")
+	}
+
+	pemKey := []byte(text)
+	if _, err := parseRSAPrivateKey(pemKey); err != nil {
+		return nil, err
+	}
+	return pemKey, nil
+}
+
+// parseRSAPrivateKey は PKCS#1 または PKCS#8 の RSA 秘密鍵を読み込む
+func parseRSAPrivateKey(pemKey []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(pemKey)
+	if block == nil {
+		return nil, errors.New("invalid PEM content")
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("private key is not an RSA key")
+	}
+	return key, nil
+}
@@ -0,0 +1,304 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github-setup-app/domain/entity"
+)
+
+// profileFile は設定ファイル（YAML/JSON）の構造
+type profileFile struct {
+	Name        string          `yaml:"name" json:"name"`
+	Files       *[]profileEntry `yaml:"files" json:"files"`
+	Labels      *[]labelEntry   `yaml:"labels" json:"labels"`
+	PruneLabels *bool           `yaml:"prune_labels" json:"prune_labels"`
+	Secrets     *[]secretEntry  `yaml:"secrets" json:"secrets"`
+	Workflows   *workflowsEntry `yaml:"workflows" json:"workflows"`
+	License     *licenseEntry   `yaml:"license" json:"license"`
+	// TemplateRepository を指定すると、そのリポジトリのファイルもコピーする
+	TemplateRepository *templateRepositoryEntry `yaml:"template_repository" json:"template_repository"`
+	// BackfillPruneLabels は既存リポジトリへの適用でもラベルを削除するか
+	BackfillPruneLabels bool `yaml:"backfill_prune_labels" json:"backfill_prune_labels"`
+}
+
+type profileEntry struct {
+	Path        string `yaml:"path" json:"path"`
+	Message     string `yaml:"message" json:"message"`
+	Content     string `yaml:"content" json:"content"`
+	ContentFile string `yaml:"content_file" json:"content_file"`
+	Builtin     string `yaml:"builtin" json:"builtin"`
+	OnConflict  string `yaml:"on_conflict" json:"on_conflict"`
+	// Raw が true の場合は内容をテンプレートとして描画しない
+	Raw bool `yaml:"raw" json:"raw"`
+}
+
+type labelEntry struct {
+	Name        string   `yaml:"name" json:"name"`
+	Color       string   `yaml:"color" json:"color"`
+	Description string   `yaml:"description" json:"description"`
+	Aliases     []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+}
+
+type secretEntry struct {
+	Name   string `yaml:"name" json:"name"`
+	Source string `yaml:"source" json:"source"`
+	Value  string `yaml:"value" json:"value"`
+	Env    string `yaml:"env" json:"env"`
+}
+
+type licenseEntry struct {
+	Default    string             `yaml:"default" json:"default"`
+	OnConflict string             `yaml:"on_conflict" json:"on_conflict"`
+	Rules      []licenseRuleEntry `yaml:"rules" json:"rules"`
+}
+
+type licenseRuleEntry struct {
+	Owner      string `yaml:"owner" json:"owner"`
+	Name       string `yaml:"name" json:"name"`
+	Visibility string `yaml:"visibility" json:"visibility"`
+	Topic      string `yaml:"topic" json:"topic"`
+	License    string `yaml:"license" json:"license"`
+}
+
+type templateRepositoryEntry struct {
+	Repository string   `yaml:"repository" json:"repository"`
+	Ref        string   `yaml:"ref" json:"ref"`
+	Path       string   `yaml:"path" json:"path"`
+	Include    []string `yaml:"include" json:"include"`
+	Exclude    []string `yaml:"exclude" json:"exclude"`
+	Raw        bool     `yaml:"raw" json:"raw"`
+	OnConflict string   `yaml:"on_conflict" json:"on_conflict"`
+}
+
+type workflowsEntry struct {
+	SetupLabels *bool `yaml:"setup_labels" json:"setup_labels"`
+}
+
+// builtinFiles は設定ファイルから builtin で参照できる組み込みファイル
+var builtinFiles = map[string]func() entity.File{
+	"license":      entity.DefaultLicenseFile,
+	"contributing": entity.DefaultContributingFile,
+	"readme":       entity.DefaultReadmeFile,
+}
+
+// LoadProfile は path のセットアッププロファイルを読み込んで検証する。
+// 省略された files / labels / workflows / license は組み込みのデフォルトを引き継ぐ。
+// license を省略して files に LICENSE を含めた場合は、files の LICENSE を使う。
+// secrets を省略して setup_labels を有効にした場合は、ワークフローに必要なシークレットを登録する。
+func LoadProfile(path string) (*entity.SetupProfile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read profile: %w", err)
+	}
+
+	var pf profileFile
+	if err := decodeFile(path, data, &pf); err != nil {
+		return nil, fmt.Errorf("failed to parse profile %s: %w", path, err)
+	}
+
+	profile, err := pf.toEntity(filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+	if err := profile.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid profile %s: %w", path, err)
+	}
+
+	return profile, nil
+}
+
+// decodeFile は拡張子が .json なら JSON、それ以外は YAML として未知のフィールドを拒否して読み込む
+func decodeFile(path string, data []byte, v any) error {
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		return dec.Decode(v)
+	}
+
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	return dec.Decode(v)
+}
+
+func (pf profileFile) toEntity(baseDir string) (*entity.SetupProfile, error) {
+	profile := entity.DefaultSetupProfile()
+	if pf.Name != "" {
+		profile.Name = pf.Name
+	}
+
+	if pf.Files != nil {
+		profile.Files = make([]entity.File, 0, len(*pf.Files))
+		for i, e := range *pf.Files {
+			file, err := e.toEntity(baseDir)
+			if err != nil {
+				return nil, fmt.Errorf("files[%d]: %w", i, err)
+			}
+			profile.Files = append(profile.Files, file)
+		}
+	}
+
+	switch {
+	case pf.License != nil:
+		profile.License = pf.License.toEntity()
+	case pf.Files != nil && slices.ContainsFunc(profile.Files, func(f entity.File) bool { return f.Path == entity.LicenseFilePath }):
+		profile.License = nil
+	}
+
+	if pf.TemplateRepository != nil {
+		profile.TemplateSource = pf.TemplateRepository.toEntity()
+	}
+
+	if pf.Labels != nil {
+		profile.Labels = make([]entity.Label, 0, len(*pf.Labels))
+		for _, e := range *pf.Labels {
+			profile.Labels = append(profile.Labels, entity.Label{
+				Name:        e.Name,
+				Color:       strings.TrimPrefix(e.Color, "#"),
+				Description: e.Description,
+				Aliases:     e.Aliases,
+			})
+		}
+	}
+	if pf.PruneLabels != nil {
+		profile.PruneLabels = *pf.PruneLabels
+	}
+	profile.BackfillPruneLabels = pf.BackfillPruneLabels
+
+	if pf.Secrets != nil {
+		profile.Secrets = make([]entity.Secret, 0, len(*pf.Secrets))
+		for i, e := range *pf.Secrets {
+			secret, err := e.toEntity()
+			if err != nil {
+				return nil, fmt.Errorf("secrets[%d]: %w", i, err)
+			}
+			profile.Secrets = append(profile.Secrets, secret)
+		}
+	}
+
+	if pf.Workflows != nil && pf.Workflows.SetupLabels != nil {
+		profile.Workflows.SetupLabels = *pf.Workflows.SetupLabels
+	}
+	if pf.Secrets == nil && profile.Workflows.SetupLabels {
+		profile.Secrets = entity.SetupLabelsWorkflowSecrets()
+	}
+
+	return profile, nil
+}
+
+func (e licenseEntry) toEntity() *entity.LicenseSelection {
+	selection := &entity.LicenseSelection{
+		Default:    e.Default,
+		OnConflict: entity.ConflictPolicy(e.OnConflict),
+	}
+	for _, r := range e.Rules {
+		selection.Rules = append(selection.Rules, entity.LicenseRule{
+			Owner:      r.Owner,
+			Name:       r.Name,
+			Visibility: r.Visibility,
+			Topic:      r.Topic,
+			License:    r.License,
+		})
+	}
+	return selection
+}
+
+func (e templateRepositoryEntry) toEntity() *entity.TemplateSource {
+	owner, name, _ := strings.Cut(e.Repository, "/")
+	return &entity.TemplateSource{
+		Owner:      owner,
+		Name:       name,
+		Ref:        e.Ref,
+		Path:       e.Path,
+		Include:    e.Include,
+		Exclude:    e.Exclude,
+		Raw:        e.Raw,
+		OnConflict: entity.ConflictPolicy(e.OnConflict),
+	}
+}
+
+func (e profileEntry) toEntity(baseDir string) (entity.File, error) {
+	sources := 0
+	for _, s := range []string{e.Content, e.ContentFile, e.Builtin} {
+		if s != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return entity.File{}, fmt.Errorf("exactly one of content, content_file or builtin is required")
+	}
+
+	if e.Builtin != "" {
+		newFile, ok := builtinFiles[e.Builtin]
+		if !ok {
+			return entity.File{}, fmt.Errorf("unknown builtin %q", e.Builtin)
+		}
+		file := newFile()
+		if e.Path != "" {
+			file.Path = e.Path
+		}
+		if e.Message != "" {
+			file.Message = e.Message
+		}
+		file.OnConflict = entity.ConflictPolicy(e.OnConflict)
+		return file, nil
+	}
+
+	content := e.Content
+	if e.ContentFile != "" {
+		path := e.ContentFile
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return entity.File{}, fmt.Errorf("failed to read content_file: %w", err)
+		}
+		content = string(data)
+	}
+
+	message := e.Message
+	if message == "" {
+		message = "Add " + e.Path
+	}
+
+	return entity.File{
+		Path:       e.Path,
+		Content:    content,
+		Message:    message,
+		OnConflict: entity.ConflictPolicy(e.OnConflict),
+		Raw:        e.Raw,
+	}, nil
+}
+
+func (e secretEntry) toEntity() (entity.Secret, error) {
+	secret := entity.Secret{Name: e.Name, Source: entity.SecretSource(e.Source)}
+
+	switch {
+	case e.Env != "":
+		if e.Source != "" || e.Value != "" {
+			return entity.Secret{}, fmt.Errorf("env cannot be combined with source or value")
+		}
+		value, ok := os.LookupEnv(e.Env)
+		if !ok {
+			return entity.Secret{}, fmt.Errorf("environment variable %s is not set", e.Env)
+		}
+		secret.Source = entity.SecretSourceValue
+		secret.Value = value
+	case e.Value != "":
+		if e.Source != "" && e.Source != string(entity.SecretSourceValue) {
+			return entity.Secret{}, fmt.Errorf("value cannot be combined with source %q", e.Source)
+		}
+		secret.Source = entity.SecretSourceValue
+		secret.Value = e.Value
+	}
+
+	return secret, nil
+}
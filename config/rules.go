@@ -0,0 +1,89 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github-setup-app/domain/entity"
+)
+
+// rulesFile は SETUP_RULES_PATH の設定ファイル（YAML/JSON）の構造
+type rulesFile struct {
+	// Profiles はプロファイル名と設定ファイルのパス（rules ファイルからの相対パス）
+	Profiles       map[string]string `yaml:"profiles" json:"profiles"`
+	DefaultProfile string            `yaml:"default_profile" json:"default_profile"`
+	Rules          []ruleEntry       `yaml:"rules" json:"rules"`
+}
+
+type ruleEntry struct {
+	Match   matchEntry `yaml:"match" json:"match"`
+	Profile string     `yaml:"profile" json:"profile"`
+	Skip    bool       `yaml:"skip" json:"skip"`
+}
+
+type matchEntry struct {
+	Owner              string   `yaml:"owner" json:"owner"`
+	Name               string   `yaml:"name" json:"name"`
+	Visibility         string   `yaml:"visibility" json:"visibility"`
+	Fork               *bool    `yaml:"fork" json:"fork"`
+	Archived           *bool    `yaml:"archived" json:"archived"`
+	IsTemplate         *bool    `yaml:"is_template" json:"is_template"`
+	FromTemplate       *bool    `yaml:"from_template" json:"from_template"`
+	TemplateRepository string   `yaml:"template_repository" json:"template_repository"`
+	Topics             []string `yaml:"topics" json:"topics"`
+}
+
+// LoadRules は path のルールと、ルールが参照するプロファイルを読み込んで検証する。
+// defaultProfile は profiles で "default" を指定しない場合の default プロファイルになる。
+func LoadRules(path string, defaultProfile *entity.SetupProfile) (map[string]*entity.SetupProfile, entity.SetupRules, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, entity.SetupRules{}, fmt.Errorf("failed to read rules: %w", err)
+	}
+
+	var rf rulesFile
+	if err := decodeFile(path, data, &rf); err != nil {
+		return nil, entity.SetupRules{}, fmt.Errorf("failed to parse rules %s: %w", path, err)
+	}
+
+	profiles := map[string]*entity.SetupProfile{
+		entity.DefaultProfileName: defaultProfile,
+	}
+	for name, profilePath := range rf.Profiles {
+		if !filepath.IsAbs(profilePath) {
+			profilePath = filepath.Join(filepath.Dir(path), profilePath)
+		}
+		profile, err := LoadProfile(profilePath)
+		if err != nil {
+			return nil, entity.SetupRules{}, fmt.Errorf("profiles.%s: %w", name, err)
+		}
+		// 実行記録などに表示する名前はルールで参照する名前に揃える
+		profile.Name = name
+		profiles[name] = profile
+	}
+
+	rules := entity.SetupRules{DefaultProfile: rf.DefaultProfile}
+	for _, e := range rf.Rules {
+		rules.Rules = append(rules.Rules, entity.SetupRule{
+			Match: entity.RuleMatch{
+				Owner:              e.Match.Owner,
+				Name:               e.Match.Name,
+				Visibility:         e.Match.Visibility,
+				Fork:               e.Match.Fork,
+				Archived:           e.Match.Archived,
+				IsTemplate:         e.Match.IsTemplate,
+				FromTemplate:       e.Match.FromTemplate,
+				TemplateRepository: e.Match.TemplateRepository,
+				Topics:             e.Match.Topics,
+			},
+			Profile: e.Profile,
+			Skip:    e.Skip,
+		})
+	}
+	if err := rules.Validate(profiles); err != nil {
+		return nil, entity.SetupRules{}, fmt.Errorf("invalid rules %s: %w", path, err)
+	}
+
+	return profiles, rules, nil
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	"github-setup-app/domain/entity"
+)
+
+// Setup はセットアップに使うプロファイルとルール
+type Setup struct {
+	// Profile は default プロファイル
+	Profile  *entity.SetupProfile
+	Profiles map[string]*entity.SetupProfile
+	Rules    entity.SetupRules
+}
+
+// LoadSetup は profilePath のプロファイル（空なら組み込みのデフォルト）と
+// rulesPath のルール（空ならルールなし）を読み込んで検証する
+func LoadSetup(profilePath, rulesPath string) (*Setup, error) {
+	profile := entity.DefaultSetupProfile()
+	if profilePath != "" {
+		var err error
+		profile, err = LoadProfile(profilePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	setup := &Setup{
+		Profile:  profile,
+		Profiles: map[string]*entity.SetupProfile{entity.DefaultProfileName: profile},
+	}
+	if rulesPath != "" {
+		profiles, rules, err := LoadRules(rulesPath, profile)
+		if err != nil {
+			return nil, err
+		}
+		setup.Profiles = profiles
+		setup.Rules = rules
+	}
+
+	return setup, nil
+}
+
+// MarshalLabels はラベルをプロファイルの labels にそのまま書ける YAML にする
+func MarshalLabels(labels []entity.Label) ([]byte, error) {
+	entries := make([]labelEntry, 0, len(labels))
+	for _, l := range labels {
+		entries = append(entries, labelEntry{
+			Name:        l.Name,
+			Color:       l.Color,
+			Description: l.Description,
+			Aliases:     l.Aliases,
+		})
+	}
+
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(struct {
+		Labels []labelEntry `yaml:"labels"`
+	}{entries}); err != nil {
+		return nil, fmt.Errorf("failed to marshal labels: %w", err)
+	}
+	return buf.Bytes(), nil
+}
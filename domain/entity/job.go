@@ -0,0 +1,43 @@
+package entity
+
+import "time"
+
+type JobType string
+
+const (
+	JobTypeSetupRepository JobType = "setup_repository"
+	JobTypeDeleteWorkflow  JobType = "delete_workflow"
+)
+
+type JobStatus string
+
+const (
+	JobStatusPending JobStatus = "pending"
+	JobStatusRunning JobStatus = "running"
+	// JobStatusFailed は再試行回数を使い切ったジョブ
+	JobStatusFailed JobStatus = "failed"
+)
+
+// Job は Webhook から非同期に実行する処理
+type Job struct {
+	ID         string     `json:"id"`
+	Type       JobType    `json:"type"`
+	Repository Repository `json:"repository"`
+	// Profile はセットアップに使うプロファイル名。空なら実行時にルールで選ぶ
+	Profile string `json:"profile,omitempty"`
+	// Backfill は管理 API から登録した既存リポジトリのセットアップ。
+	// 既存ラベルは PruneLabels またはプロファイルの backfill_prune_labels の場合だけ削除する
+	Backfill    bool `json:"backfill,omitempty"`
+	PruneLabels bool `json:"prune_labels,omitempty"`
+	// DeliveryID はジョブを登録した Webhook の配信 ID（ログの関連付けに使う）
+	DeliveryID string `json:"delivery_id,omitempty"`
+	// TraceParent はジョブを登録したスパン（W3C traceparent 形式）。実行時のスパンからリンクする
+	TraceParent string    `json:"trace_parent,omitempty"`
+	Status      JobStatus `json:"status"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	// RunAfter より前には実行しない（再試行の待ち時間）
+	RunAfter time.Time `json:"run_after"`
+}
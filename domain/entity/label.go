@@ -1,16 +1,18 @@
 package entity
 
 type Label struct {
-	Name        string
-	Color       string
-	Description string
+	Name        string `json:"name"`
+	Color       string `json:"color"`
+	Description string `json:"description"`
+	// Aliases は以前の名前。既存ラベルがこの名前なら削除せずにリネームする
+	Aliases []string `json:"aliases,omitempty"`
 }
 
 func DefaultLabels() []Label {
 	return []Label{
 		{Name: "bug", Color: "d73a4a", Description: "バグ報告"},
-		{Name: "feature", Color: "a2eeef", Description: "新機能追加"},
-		{Name: "docs", Color: "0075ca", Description: "ドキュメント改善"},
+		{Name: "feature", Color: "a2eeef", Description: "新機能追加", Aliases: []string{"enhancement"}},
+		{Name: "docs", Color: "0075ca", Description: "ドキュメント改善", Aliases: []string{"documentation"}},
 		{Name: "refactor", Color: "fbca04", Description: "リファクタリング"},
 		{Name: "other", Color: "5319e7", Description: "その他"},
 	}
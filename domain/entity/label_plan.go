@@ -0,0 +1,165 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+)
+
+type LabelOperationType string
+
+const (
+	LabelOperationCreate LabelOperationType = "create"
+	LabelOperationUpdate LabelOperationType = "update"
+	LabelOperationRename LabelOperationType = "rename"
+	LabelOperationDelete LabelOperationType = "delete"
+)
+
+// LabelOperation はラベルに対する1つの操作
+type LabelOperation struct {
+	Type LabelOperationType `json:"type"`
+	// Name は操作対象の既存ラベル名（create の場合は作成するラベル名）
+	Name string `json:"name"`
+	// Label は操作後のラベル（delete の場合は nil）
+	Label *Label `json:"label,omitempty"`
+	// Changes は変更されるフィールド（name, color, description）
+	Changes []string `json:"changes,omitempty"`
+}
+
+func (op LabelOperation) String() string {
+	switch op.Type {
+	case LabelOperationRename:
+		return fmt.Sprintf("rename %q -> %q", op.Name, op.Label.Name)
+	case LabelOperationUpdate:
+		return fmt.Sprintf("update %q (%s)", op.Name, strings.Join(op.Changes, ", "))
+	default:
+		return fmt.Sprintf("%s %q", op.Type, op.Name)
+	}
+}
+
+// LabelPlan は既存ラベルを目的のラベルに揃えるための操作一覧
+type LabelPlan struct {
+	Operations []LabelOperation `json:"operations"`
+}
+
+func (p LabelPlan) IsEmpty() bool {
+	return len(p.Operations) == 0
+}
+
+// Count は種類ごとの操作数を返す
+func (p LabelPlan) Count(t LabelOperationType) int {
+	n := 0
+	for _, op := range p.Operations {
+		if op.Type == t {
+			n++
+		}
+	}
+	return n
+}
+
+func (p LabelPlan) Summary() string {
+	return fmt.Sprintf("%d to create, %d to update, %d to rename, %d to delete",
+		p.Count(LabelOperationCreate),
+		p.Count(LabelOperationUpdate),
+		p.Count(LabelOperationRename),
+		p.Count(LabelOperationDelete))
+}
+
+// PlanLabels は existing を desired に揃えるための操作を計算する。
+// 操作は rename, update, create, delete の順に並ぶ。
+// prune が false の場合、desired にないラベルは削除しない。
+func PlanLabels(existing, desired []Label, prune bool) LabelPlan {
+	// ラベル名は大文字小文字を区別しない
+	current := make(map[string]Label, len(existing))
+	for _, l := range existing {
+		current[strings.ToLower(l.Name)] = l
+	}
+
+	// 名前が一致する既存ラベルはエイリアスによるリネームの対象にしない
+	used := make(map[string]bool, len(existing))
+	for _, want := range desired {
+		key := strings.ToLower(want.Name)
+		if _, ok := current[key]; ok {
+			used[key] = true
+		}
+	}
+
+	var renames, updates, creates, deletes []LabelOperation
+
+	for _, want := range desired {
+		key := strings.ToLower(want.Name)
+		target := want
+		target.Aliases = nil
+
+		if found, ok := current[key]; ok {
+			if changes := labelChanges(found, target); len(changes) > 0 {
+				updates = append(updates, LabelOperation{
+					Type:    LabelOperationUpdate,
+					Name:    found.Name,
+					Label:   &target,
+					Changes: changes,
+				})
+			}
+			continue
+		}
+
+		renamed := false
+		for _, alias := range want.Aliases {
+			aliasKey := strings.ToLower(alias)
+			found, ok := current[aliasKey]
+			if !ok || used[aliasKey] {
+				continue
+			}
+			used[aliasKey] = true
+			renames = append(renames, LabelOperation{
+				Type:    LabelOperationRename,
+				Name:    found.Name,
+				Label:   &target,
+				Changes: labelChanges(found, target),
+			})
+			renamed = true
+			break
+		}
+		if renamed {
+			continue
+		}
+
+		creates = append(creates, LabelOperation{
+			Type:  LabelOperationCreate,
+			Name:  target.Name,
+			Label: &target,
+		})
+	}
+
+	if prune {
+		for _, l := range existing {
+			if used[strings.ToLower(l.Name)] {
+				continue
+			}
+			deletes = append(deletes, LabelOperation{
+				Type: LabelOperationDelete,
+				Name: l.Name,
+			})
+		}
+	}
+
+	ops := make([]LabelOperation, 0, len(renames)+len(updates)+len(creates)+len(deletes))
+	ops = append(ops, renames...)
+	ops = append(ops, updates...)
+	ops = append(ops, creates...)
+	ops = append(ops, deletes...)
+	return LabelPlan{Operations: ops}
+}
+
+func labelChanges(from, to Label) []string {
+	var changes []string
+	if from.Name != to.Name {
+		changes = append(changes, "name")
+	}
+	if !strings.EqualFold(from.Color, to.Color) {
+		changes = append(changes, "color")
+	}
+	if from.Description != to.Description {
+		changes = append(changes, "description")
+	}
+	return changes
+}
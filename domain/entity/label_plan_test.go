@@ -0,0 +1,125 @@
+package entity
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanLabels(t *testing.T) {
+	bug := Label{Name: "bug", Color: "d73a4a", Description: "Something isn't working"}
+
+	tests := []struct {
+		name     string
+		existing []Label
+		desired  []Label
+		prune    bool
+		// want は操作を String() で表したもの
+		want []string
+	}{
+		{
+			name:     "up to date",
+			existing: []Label{bug},
+			desired:  []Label{bug},
+			want:     []string{},
+		},
+		{
+			name:     "create missing",
+			existing: nil,
+			desired:  []Label{bug},
+			want:     []string{`create "bug"`},
+		},
+		{
+			name:     "update color and description",
+			existing: []Label{{Name: "bug", Color: "ffffff", Description: "old"}},
+			desired:  []Label{bug},
+			want:     []string{`update "bug" (color, description)`},
+		},
+		{
+			name:     "names and colors are case-insensitive",
+			existing: []Label{{Name: "Bug", Color: "D73A4A", Description: bug.Description}},
+			desired:  []Label{bug},
+			want:     []string{`update "Bug" (name)`},
+		},
+		{
+			name:     "rename via alias",
+			existing: []Label{{Name: "defect", Color: "d73a4a", Description: bug.Description}},
+			desired:  []Label{{Name: "bug", Color: "d73a4a", Description: bug.Description, Aliases: []string{"defect"}}},
+			prune:    true,
+			want:     []string{`rename "defect" -> "bug"`},
+		},
+		{
+			name:     "alias is ignored when the label already exists",
+			existing: []Label{bug, {Name: "defect"}},
+			desired:  []Label{{Name: "bug", Color: bug.Color, Description: bug.Description, Aliases: []string{"defect"}}},
+			prune:    true,
+			want:     []string{`delete "defect"`},
+		},
+		{
+			name:     "alias used by another desired label",
+			existing: []Label{{Name: "defect"}},
+			desired:  []Label{{Name: "bug", Aliases: []string{"defect"}}, {Name: "defect"}},
+			want:     []string{`create "bug"`},
+		},
+		{
+			name:     "alias renamed only once",
+			existing: []Label{{Name: "old"}},
+			desired:  []Label{{Name: "a", Aliases: []string{"old"}}, {Name: "b", Aliases: []string{"old"}}},
+			want:     []string{`rename "old" -> "a"`, `create "b"`},
+		},
+		{
+			name:     "extra labels kept without prune",
+			existing: []Label{bug, {Name: "wontfix"}},
+			desired:  []Label{bug},
+			want:     []string{},
+		},
+		{
+			name:     "extra labels deleted with prune",
+			existing: []Label{bug, {Name: "wontfix"}},
+			desired:  []Label{bug},
+			prune:    true,
+			want:     []string{`delete "wontfix"`},
+		},
+		{
+			name:     "operations are ordered",
+			existing: []Label{{Name: "stale"}, {Name: "defect"}, {Name: "docs", Color: "000000"}},
+			desired: []Label{
+				{Name: "new"},
+				{Name: "docs", Color: "0075ca"},
+				{Name: "bug", Aliases: []string{"defect"}},
+			},
+			prune: true,
+			want:  []string{`rename "defect" -> "bug"`, `update "docs" (color)`, `create "new"`, `delete "stale"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan := PlanLabels(tt.existing, tt.desired, tt.prune)
+
+			got := make([]string, 0, len(plan.Operations))
+			for _, op := range plan.Operations {
+				got = append(got, op.String())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("PlanLabels() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlanLabelsDropsAliases(t *testing.T) {
+	plan := PlanLabels(
+		[]Label{{Name: "defect"}},
+		[]Label{{Name: "bug", Aliases: []string{"defect"}}, {Name: "docs", Aliases: []string{"documentation"}}},
+		false,
+	)
+
+	for _, op := range plan.Operations {
+		if op.Label == nil || op.Label.Aliases != nil {
+			t.Errorf("%s: Label = %+v, want label without aliases", op, op.Label)
+		}
+	}
+	if plan.Count(LabelOperationRename) != 1 || plan.Count(LabelOperationCreate) != 1 {
+		t.Errorf("Summary() = %s", plan.Summary())
+	}
+}
@@ -0,0 +1,196 @@
+package entity
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// LicenseFilePath はライセンスファイルの配置先
+const LicenseFilePath = "LICENSE"
+
+// licenses/ には SPDX 識別子をファイル名にしたライセンスのテンプレートを置く
+//
+//go:embed licenses/*.txt
+var licenseTemplates embed.FS
+
+// License はカタログに登録されたライセンス
+type License struct {
+	// ID は SPDX 識別子（独自のライセンスは LicenseRef- で始める）
+	ID      string
+	Content string
+}
+
+// licenseAliases は設定ファイルで SPDX 識別子の代わりに使える名前
+var licenseAliases = map[string]string{
+	"proprietary": "LicenseRef-Proprietary",
+}
+
+// LookupLicense は SPDX 識別子または別名でライセンスを探す（大文字小文字は区別しない）
+func LookupLicense(id string) (License, bool) {
+	if alias, ok := licenseAliases[strings.ToLower(id)]; ok {
+		id = alias
+	}
+	for _, known := range LicenseIDs() {
+		if strings.EqualFold(known, id) {
+			data, err := licenseTemplates.ReadFile("licenses/" + known + ".txt")
+			if err != nil {
+				return License{}, false
+			}
+			return License{ID: known, Content: string(data)}, true
+		}
+	}
+	return License{}, false
+}
+
+// LicenseIDs はカタログに登録されている SPDX 識別子を返す
+func LicenseIDs() []string {
+	entries, _ := licenseTemplates.ReadDir("licenses")
+	ids := make([]string, 0, len(entries))
+	for _, e := range entries {
+		ids = append(ids, strings.TrimSuffix(e.Name(), ".txt"))
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// LicenseRule は条件に一致するリポジトリに使うライセンス。空の条件は無視する
+type LicenseRule struct {
+	Owner string
+	// Name はリポジトリ名に対する glob
+	Name       string
+	Visibility string
+	Topic      string
+	License    string
+}
+
+func (r LicenseRule) matches(data TemplateData) bool {
+	if r.Owner != "" && !strings.EqualFold(r.Owner, data.Owner) {
+		return false
+	}
+	if r.Name != "" {
+		if ok, _ := path.Match(r.Name, data.Repo); !ok {
+			return false
+		}
+	}
+	if r.Visibility != "" && !strings.EqualFold(r.Visibility, data.Visibility) {
+		return false
+	}
+	if r.Topic != "" && !containsFold(data.Topics, r.Topic) {
+		return false
+	}
+	return true
+}
+
+// LicenseSelection はリポジトリごとに作成するライセンスを選ぶ設定
+type LicenseSelection struct {
+	// Default はどのルールにも一致しない場合のライセンス。空なら作成しない
+	Default string
+	// Rules は上から順に評価し、最初に一致したものを使う
+	Rules      []LicenseRule
+	OnConflict ConflictPolicy
+}
+
+// Select はリポジトリに使うライセンスの ID を返す。作成しない場合は空
+func (s LicenseSelection) Select(data TemplateData) string {
+	for _, r := range s.Rules {
+		if r.matches(data) {
+			return r.License
+		}
+	}
+	return s.Default
+}
+
+// File は選んだライセンスを data で描画した LicenseFile を返す。作成しない場合は nil
+func (s LicenseSelection) File(data TemplateData) (*LicenseFile, error) {
+	id := s.Select(data)
+	if id == "" {
+		return nil, nil
+	}
+	license, ok := LookupLicense(id)
+	if !ok {
+		return nil, fmt.Errorf("unknown license %q", id)
+	}
+
+	file, err := File{Path: LicenseFilePath, Content: license.Content}.Render(data)
+	if err != nil {
+		return nil, err
+	}
+	return &LicenseFile{
+		LicenseID:  license.ID,
+		Content:    file.Content,
+		OnConflict: s.OnConflict,
+	}, nil
+}
+
+// Validate はライセンスの ID とルールの条件を検証する
+func (s LicenseSelection) Validate() error {
+	var errs []error
+	if s.Default != "" {
+		if _, ok := LookupLicense(s.Default); !ok {
+			errs = append(errs, fmt.Errorf("license.default: unknown license %q (available: %s)", s.Default, strings.Join(LicenseIDs(), ", ")))
+		}
+	}
+	for i, r := range s.Rules {
+		if r.Owner == "" && r.Name == "" && r.Visibility == "" && r.Topic == "" {
+			errs = append(errs, fmt.Errorf("license.rules[%d]: at least one of owner, name, visibility or topic is required", i))
+		}
+		if _, err := path.Match(r.Name, ""); err != nil {
+			errs = append(errs, fmt.Errorf("license.rules[%d]: invalid name pattern %q", i, r.Name))
+		}
+		switch strings.ToLower(r.Visibility) {
+		case "", "public", "private", "internal":
+		default:
+			errs = append(errs, fmt.Errorf("license.rules[%d]: unknown visibility %q", i, r.Visibility))
+		}
+		if _, ok := LookupLicense(r.License); !ok {
+			errs = append(errs, fmt.Errorf("license.rules[%d]: unknown license %q (available: %s)", i, r.License, strings.Join(LicenseIDs(), ", ")))
+		}
+	}
+	if s.OnConflict != "" && !s.OnConflict.IsValid() {
+		errs = append(errs, fmt.Errorf("license.on_conflict: unknown on_conflict %q", s.OnConflict))
+	}
+	return errors.Join(errs...)
+}
+
+// LicenseFile はカタログから選んで描画したライセンスファイル
+type LicenseFile struct {
+	LicenseID  string
+	Content    string
+	OnConflict ConflictPolicy
+}
+
+func (l LicenseFile) GetPath() string    { return LicenseFilePath }
+func (l LicenseFile) GetContent() string { return l.Content }
+func (l LicenseFile) GetMessage() string { return "Add LICENSE file (" + l.LicenseID + ")" }
+func (l LicenseFile) GetMode() string    { return FileModeRegular }
+
+// GetConflictPolicy は未指定の場合 skip を返す（既存のライセンスを変更しない）
+func (l LicenseFile) GetConflictPolicy() ConflictPolicy {
+	if l.OnConflict == "" {
+		return ConflictSkip
+	}
+	return l.OnConflict
+}
+
+// DefaultLicenseFile はカタログの MIT License を File として返す
+func DefaultLicenseFile() File {
+	license, _ := LookupLicense("MIT")
+	return File{
+		Path:    LicenseFilePath,
+		Message: "Add LICENSE file",
+		Content: license.Content,
+	}
+}
+
+func containsFold(values []string, target string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, target) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,44 @@
+package entity
+
+// PlannedActionType はドライランで記録した GitHub への変更の種類
+type PlannedActionType string
+
+const (
+	PlannedCreateFile   PlannedActionType = "create_file"
+	PlannedCreateFiles  PlannedActionType = "create_files"
+	PlannedDeleteFile   PlannedActionType = "delete_file"
+	PlannedCreateSecret PlannedActionType = "create_secret"
+	PlannedCreateLabel  PlannedActionType = "create_label"
+	PlannedUpdateLabel  PlannedActionType = "update_label"
+	PlannedDeleteLabel  PlannedActionType = "delete_label"
+)
+
+type PlannedFile struct {
+	Path    string `json:"path"`
+	Message string `json:"message,omitempty"`
+	Mode    string `json:"mode"`
+	Content string `json:"content"`
+}
+
+// PlannedAction はドライランで実行せずに記録した変更。シークレットの値は記録しない
+type PlannedAction struct {
+	Type       PlannedActionType `json:"type"`
+	Repository string            `json:"repository"`
+	// Message はコミットメッセージ
+	Message string        `json:"message,omitempty"`
+	Files   []PlannedFile `json:"files,omitempty"`
+	Path    string        `json:"path,omitempty"`
+	Secret  string        `json:"secret,omitempty"`
+	// Name は変更・削除するラベルの現在の名前
+	Name  string `json:"name,omitempty"`
+	Label *Label `json:"label,omitempty"`
+}
+
+func NewPlannedFile(file FileContent) PlannedFile {
+	return PlannedFile{
+		Path:    file.GetPath(),
+		Message: file.GetMessage(),
+		Mode:    file.GetMode(),
+		Content: file.GetContent(),
+	}
+}
@@ -0,0 +1,234 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SecretSource はシークレット値の取得元
+type SecretSource string
+
+const (
+	// SecretSourceLabelAppID はラベル操作App の ID を値として使う
+	SecretSourceLabelAppID SecretSource = "label_app_id"
+	// SecretSourceLabelAppPrivateKey はラベル操作App の秘密鍵を値として使う
+	SecretSourceLabelAppPrivateKey SecretSource = "label_app_private_key"
+	// SecretSourceValue は設定ファイルで解決済みの値をそのまま使う
+	SecretSourceValue SecretSource = "value"
+)
+
+type Secret struct {
+	Name   string
+	Source SecretSource
+	Value  string
+}
+
+// WorkflowOptions はプロファイルで切り替えられるワークフローの設定
+type WorkflowOptions struct {
+	// SetupLabels が true の場合は従来どおり setup-labels ワークフローでラベルを作成する。
+	// false の場合は Labels API で直接ラベルを同期する。
+	SetupLabels bool
+}
+
+// SetupProfile は新規リポジトリに適用する内容一式
+type SetupProfile struct {
+	Name      string
+	Files     []File
+	Labels    []Label
+	Secrets   []Secret
+	Workflows WorkflowOptions
+	// License が設定されている場合、カタログから選んだライセンスを LICENSE として作成する
+	License *LicenseSelection
+	// TemplateSource が設定されている場合、テンプレートリポジトリのファイルもコピーする
+	TemplateSource *TemplateSource
+	// PruneLabels が true の場合、Labels にない既存ラベルを削除する
+	PruneLabels bool
+	// BackfillPruneLabels が true の場合、既存リポジトリへの適用（バックフィル）でも PruneLabels に従って削除する。
+	// 既存の Issue や PR からラベルが外れるため、既定では削除しない
+	BackfillPruneLabels bool
+}
+
+func DefaultSetupProfile() *SetupProfile {
+	return &SetupProfile{
+		Name: "default",
+		Files: []File{
+			DefaultContributingFile(),
+			DefaultReadmeFile(),
+		},
+		License:     &LicenseSelection{Default: "MIT"},
+		Labels:      DefaultLabels(),
+		PruneLabels: true,
+	}
+}
+
+// SetupLabelsWorkflowSecrets は setup-labels ワークフローが必要とするシークレット
+func SetupLabelsWorkflowSecrets() []Secret {
+	return []Secret{
+		{Name: "APP_ID", Source: SecretSourceLabelAppID},
+		{Name: "APP_PRIVATE_KEY", Source: SecretSourceLabelAppPrivateKey},
+	}
+}
+
+// TemplateFiles はプロファイルから作成するファイルを data で描画して返す（ライセンスは最初、ワークフローは最後）。
+// sourced はテンプレートリポジトリから取得したファイルで、同じパスのプロファイルのファイルを置き換える。
+// ワークフローは ${{ }} 式を含むため描画しない。
+func (p *SetupProfile) TemplateFiles(data TemplateData, sourced []File) ([]FileContent, error) {
+	files := make([]FileContent, 0, len(p.Files)+len(sourced)+2)
+	// 作成しないパス（ライセンスとワークフローは専用の設定を優先する）
+	skip := make(map[string]bool)
+	if p.License != nil {
+		license, err := p.License.File(data)
+		if err != nil {
+			return nil, err
+		}
+		if license != nil {
+			files = append(files, *license)
+			skip[license.GetPath()] = true
+		}
+	}
+	if p.Workflows.SetupLabels {
+		skip[SetupLabelsWorkflowPath] = true
+	}
+
+	overridden := make(map[string]bool)
+	for _, f := range sourced {
+		overridden[f.Path] = true
+	}
+	for _, f := range p.Files {
+		if skip[f.Path] || overridden[f.Path] {
+			continue
+		}
+		rendered, err := f.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rendered)
+	}
+	for _, f := range sourced {
+		if skip[f.Path] {
+			continue
+		}
+		rendered, err := f.Render(data)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, rendered)
+	}
+
+	if p.Workflows.SetupLabels {
+		files = append(files, SetupLabelsWorkflow(p.Labels))
+	}
+	return files, nil
+}
+
+var (
+	labelColorPattern = regexp.MustCompile(`^[0-9a-fA-F]{6}$`)
+	secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+)
+
+// Validate はプロファイルの内容を検証し、見つかったエラーをまとめて返す
+func (p *SetupProfile) Validate() error {
+	var errs []error
+
+	paths := make(map[string]bool)
+	for i, f := range p.Files {
+		switch {
+		case f.Path == "":
+			errs = append(errs, fmt.Errorf("files[%d]: path is required", i))
+		case strings.HasPrefix(f.Path, "/") || strings.Contains(f.Path, ".."):
+			errs = append(errs, fmt.Errorf("files[%d]: path %q must be relative to the repository root", i, f.Path))
+		case paths[f.Path]:
+			errs = append(errs, fmt.Errorf("files[%d]: duplicate path %q", i, f.Path))
+		}
+		paths[f.Path] = true
+		if f.OnConflict != "" && !f.OnConflict.IsValid() {
+			errs = append(errs, fmt.Errorf("files[%d]: unknown on_conflict %q", i, f.OnConflict))
+		}
+		if !f.Raw {
+			if _, err := parseTemplate(f.Path, f.Content); err != nil {
+				errs = append(errs, fmt.Errorf("files[%d]: %w", i, err))
+			}
+		}
+	}
+	if p.License != nil {
+		if paths[LicenseFilePath] {
+			errs = append(errs, fmt.Errorf("files: %q is created from license, remove it from files", LicenseFilePath))
+		}
+		if err := p.License.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.TemplateSource != nil {
+		if err := p.TemplateSource.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if p.Workflows.SetupLabels {
+		if paths[SetupLabelsWorkflowPath] {
+			errs = append(errs, fmt.Errorf("files: %q is reserved for the setup-labels workflow", SetupLabelsWorkflowPath))
+		}
+		for _, required := range SetupLabelsWorkflowSecrets() {
+			if !p.hasSecret(required.Name) {
+				errs = append(errs, fmt.Errorf("secrets: %s is required by the setup-labels workflow", required.Name))
+			}
+		}
+	}
+
+	labels := make(map[string]bool)
+	for i, l := range p.Labels {
+		key := strings.ToLower(l.Name)
+		switch {
+		case l.Name == "":
+			errs = append(errs, fmt.Errorf("labels[%d]: name is required", i))
+		case strings.ContainsAny(l.Name, "|\n"):
+			errs = append(errs, fmt.Errorf("labels[%d]: name %q contains invalid characters", i, l.Name))
+		case labels[key]:
+			errs = append(errs, fmt.Errorf("labels[%d]: duplicate name %q", i, l.Name))
+		}
+		labels[key] = true
+		if !labelColorPattern.MatchString(l.Color) {
+			errs = append(errs, fmt.Errorf("labels[%d]: color %q must be 6 hex digits", i, l.Color))
+		}
+		for _, alias := range l.Aliases {
+			if alias == "" {
+				errs = append(errs, fmt.Errorf("labels[%d]: alias must not be empty", i))
+			}
+		}
+	}
+
+	secrets := make(map[string]bool)
+	for i, s := range p.Secrets {
+		switch {
+		case !secretNamePattern.MatchString(s.Name):
+			errs = append(errs, fmt.Errorf("secrets[%d]: invalid name %q", i, s.Name))
+		case strings.HasPrefix(strings.ToUpper(s.Name), "GITHUB_"):
+			errs = append(errs, fmt.Errorf("secrets[%d]: name %q must not start with GITHUB_", i, s.Name))
+		case secrets[strings.ToUpper(s.Name)]:
+			errs = append(errs, fmt.Errorf("secrets[%d]: duplicate name %q", i, s.Name))
+		}
+		secrets[strings.ToUpper(s.Name)] = true
+
+		switch s.Source {
+		case SecretSourceLabelAppID, SecretSourceLabelAppPrivateKey:
+		case SecretSourceValue:
+			if s.Value == "" {
+				errs = append(errs, fmt.Errorf("secrets[%d]: value is empty", i))
+			}
+		default:
+			errs = append(errs, fmt.Errorf("secrets[%d]: unknown source %q", i, s.Source))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (p *SetupProfile) hasSecret(name string) bool {
+	for _, s := range p.Secrets {
+		if strings.EqualFold(s.Name, name) {
+			return true
+		}
+	}
+	return false
+}
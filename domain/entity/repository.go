@@ -1,7 +1,13 @@
 package entity
 
 type Repository struct {
-	Owner          string
-	Name           string
-	InstallationID int64
+	Owner          string `json:"owner"`
+	Name           string `json:"name"`
+	InstallationID int64  `json:"installation_id"`
+	Archived       bool   `json:"archived,omitempty"`
+}
+
+// FullName は owner/name 形式の名前を返す
+func (r Repository) FullName() string {
+	return r.Owner + "/" + r.Name
 }
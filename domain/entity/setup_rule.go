@@ -0,0 +1,149 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// DefaultProfileName はルールで指定しない場合に使うプロファイル名
+const DefaultProfileName = "default"
+
+// RuleMatch はルールの条件。空（nil）の条件は無視し、指定した条件が全て一致した場合に一致とみなす
+type RuleMatch struct {
+	Owner string
+	// Name はリポジトリ名に対する glob
+	Name       string
+	Visibility string
+	Fork       *bool
+	Archived   *bool
+	// IsTemplate はリポジトリ自体がテンプレートリポジトリかどうか
+	IsTemplate *bool
+	// FromTemplate はテンプレートリポジトリから作成されたかどうか
+	FromTemplate *bool
+	// TemplateRepository は作成元のテンプレートリポジトリ（owner/name）に対する glob
+	TemplateRepository string
+	// Topics のいずれかがリポジトリのトピックにあれば一致
+	Topics []string
+}
+
+func (m RuleMatch) matches(repo Repository, meta RepositoryMetadata) bool {
+	if m.Owner != "" && !strings.EqualFold(m.Owner, repo.Owner) {
+		return false
+	}
+	if m.Name != "" {
+		if ok, _ := path.Match(m.Name, repo.Name); !ok {
+			return false
+		}
+	}
+	if m.Visibility != "" && !strings.EqualFold(m.Visibility, meta.Visibility) {
+		return false
+	}
+	if m.Fork != nil && *m.Fork != meta.Fork {
+		return false
+	}
+	if m.Archived != nil && *m.Archived != meta.Archived {
+		return false
+	}
+	if m.IsTemplate != nil && *m.IsTemplate != meta.IsTemplate {
+		return false
+	}
+	if m.FromTemplate != nil && *m.FromTemplate != (meta.TemplateRepository != "") {
+		return false
+	}
+	if m.TemplateRepository != "" {
+		if ok, _ := path.Match(m.TemplateRepository, meta.TemplateRepository); !ok {
+			return false
+		}
+	}
+	if len(m.Topics) > 0 && !containsAnyFold(meta.Topics, m.Topics) {
+		return false
+	}
+	return true
+}
+
+// SetupRule は条件に一致したリポジトリに適用するプロファイル。Skip の場合はセットアップしない
+type SetupRule struct {
+	Match   RuleMatch
+	Profile string
+	Skip    bool
+}
+
+// SetupRules はリポジトリの属性からプロファイルを選ぶルール
+type SetupRules struct {
+	// DefaultProfile はどのルールにも一致しない場合のプロファイル。空なら DefaultProfileName
+	DefaultProfile string
+	// Rules は上から順に評価し、最初に一致したものを使う
+	Rules []SetupRule
+}
+
+// SetupDecision はルールの評価結果
+type SetupDecision struct {
+	Profile string
+	Skip    bool
+	// Reason は決定の根拠（一致したルールの位置、または default）
+	Reason string
+}
+
+// Decide は repo に適用するプロファイル、またはセットアップしないことを決める
+func (r SetupRules) Decide(repo Repository, meta RepositoryMetadata) SetupDecision {
+	for i, rule := range r.Rules {
+		if !rule.Match.matches(repo, meta) {
+			continue
+		}
+		reason := fmt.Sprintf("rules[%d]", i)
+		if rule.Skip {
+			return SetupDecision{Skip: true, Reason: reason}
+		}
+		return SetupDecision{Profile: rule.Profile, Reason: reason}
+	}
+	return SetupDecision{Profile: r.defaultProfile(), Reason: "default"}
+}
+
+func (r SetupRules) defaultProfile() string {
+	if r.DefaultProfile == "" {
+		return DefaultProfileName
+	}
+	return r.DefaultProfile
+}
+
+// Validate はルールが参照するプロファイルが profiles にあるか、条件が正しいかを検証する
+func (r SetupRules) Validate(profiles map[string]*SetupProfile) error {
+	var errs []error
+	if _, ok := profiles[r.defaultProfile()]; !ok {
+		errs = append(errs, fmt.Errorf("default_profile: unknown profile %q", r.defaultProfile()))
+	}
+	for i, rule := range r.Rules {
+		switch {
+		case rule.Skip && rule.Profile != "":
+			errs = append(errs, fmt.Errorf("rules[%d]: skip cannot be combined with profile", i))
+		case !rule.Skip && rule.Profile == "":
+			errs = append(errs, fmt.Errorf("rules[%d]: profile or skip is required", i))
+		case rule.Profile != "":
+			if _, ok := profiles[rule.Profile]; !ok {
+				errs = append(errs, fmt.Errorf("rules[%d]: unknown profile %q", i, rule.Profile))
+			}
+		}
+		for _, pattern := range []string{rule.Match.Name, rule.Match.TemplateRepository} {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs = append(errs, fmt.Errorf("rules[%d]: invalid pattern %q", i, pattern))
+			}
+		}
+		switch strings.ToLower(rule.Match.Visibility) {
+		case "", "public", "private", "internal":
+		default:
+			errs = append(errs, fmt.Errorf("rules[%d]: unknown visibility %q", i, rule.Match.Visibility))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func containsAnyFold(values, targets []string) bool {
+	for _, t := range targets {
+		if containsFold(values, t) {
+			return true
+		}
+	}
+	return false
+}
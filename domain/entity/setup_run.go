@@ -0,0 +1,139 @@
+package entity
+
+import "time"
+
+// SetupStep はセットアップの各段階
+type SetupStep string
+
+const (
+	SetupStepSecrets SetupStep = "secrets"
+	SetupStepFiles   SetupStep = "files"
+	SetupStepLabels  SetupStep = "labels"
+	// SetupStepCleanup は setup-labels ワークフローの削除（レガシーモードのみ）
+	SetupStepCleanup SetupStep = "cleanup"
+)
+
+type StepStatus string
+
+const (
+	StepStatusPending   StepStatus = "pending"
+	StepStatusRunning   StepStatus = "running"
+	StepStatusSucceeded StepStatus = "succeeded"
+	StepStatusFailed    StepStatus = "failed"
+	StepStatusSkipped   StepStatus = "skipped"
+	// StepStatusWaiting は setup-labels ワークフローの完了待ち
+	StepStatusWaiting StepStatus = "waiting"
+)
+
+type SetupRunStatus string
+
+const (
+	SetupRunRunning   SetupRunStatus = "running"
+	SetupRunSucceeded SetupRunStatus = "succeeded"
+	SetupRunFailed    SetupRunStatus = "failed"
+	// SetupRunWaiting は setup-labels ワークフローの完了待ち
+	SetupRunWaiting SetupRunStatus = "waiting"
+)
+
+type StepState struct {
+	Step       SetupStep  `json:"step"`
+	Status     StepStatus `json:"status"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+// SetupRun は1つのリポジトリに対するセットアップの実行記録
+type SetupRun struct {
+	ID         string         `json:"id"`
+	Repository Repository     `json:"repository"`
+	Profile    string         `json:"profile"`
+	Status     SetupRunStatus `json:"status"`
+	Steps      []StepState    `json:"steps"`
+	StartedAt  time.Time      `json:"started_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+func NewSetupRun(id string, repo Repository, profile string, now time.Time) *SetupRun {
+	steps := []SetupStep{SetupStepSecrets, SetupStepFiles, SetupStepLabels, SetupStepCleanup}
+	run := &SetupRun{
+		ID:         id,
+		Repository: repo,
+		Profile:    profile,
+		Status:     SetupRunRunning,
+		StartedAt:  now,
+		UpdatedAt:  now,
+	}
+	for _, step := range steps {
+		run.Steps = append(run.Steps, StepState{Step: step, Status: StepStatusPending})
+	}
+	return run
+}
+
+func (r *SetupRun) Step(step SetupStep) *StepState {
+	for i := range r.Steps {
+		if r.Steps[i].Step == step {
+			return &r.Steps[i]
+		}
+	}
+	return nil
+}
+
+func (r *SetupRun) StartStep(step SetupStep, now time.Time) {
+	s := r.Step(step)
+	s.Status = StepStatusRunning
+	s.Error = ""
+	s.StartedAt = &now
+	s.FinishedAt = nil
+	r.UpdatedAt = now
+}
+
+// FinishStep はステップを終了する。err が nil でなければステップと実行全体を失敗にする
+func (r *SetupRun) FinishStep(step SetupStep, err error, now time.Time) {
+	s := r.Step(step)
+	s.FinishedAt = &now
+	r.UpdatedAt = now
+	if err != nil {
+		s.Status = StepStatusFailed
+		s.Error = err.Error()
+		r.Status = SetupRunFailed
+		r.FinishedAt = &now
+		return
+	}
+	s.Status = StepStatusSucceeded
+}
+
+func (r *SetupRun) SetStep(step SetupStep, status StepStatus, now time.Time) {
+	r.Step(step).Status = status
+	r.UpdatedAt = now
+}
+
+// Finish はステップの状態から実行全体の状態を決める
+func (r *SetupRun) Finish(now time.Time) {
+	r.UpdatedAt = now
+	r.Status = SetupRunSucceeded
+	for _, s := range r.Steps {
+		switch s.Status {
+		case StepStatusFailed:
+			r.Status = SetupRunFailed
+		case StepStatusWaiting, StepStatusPending, StepStatusRunning:
+			if r.Status != SetupRunFailed {
+				r.Status = SetupRunWaiting
+			}
+		}
+	}
+	if r.Status != SetupRunWaiting {
+		r.FinishedAt = &now
+	}
+}
+
+// FailedStep は失敗したステップを返す。なければ空文字
+func (r *SetupRun) FailedStep() SetupStep {
+	for _, s := range r.Steps {
+		if s.Status == StepStatusFailed {
+			return s.Step
+		}
+	}
+	return ""
+}
@@ -0,0 +1,78 @@
+package entity
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// RepositoryMetadata はテンプレートの描画やプロファイルの選択に使うリポジトリの情報
+type RepositoryMetadata struct {
+	DefaultBranch string   `json:"default_branch"`
+	Description   string   `json:"description"`
+	Visibility    string   `json:"visibility"`
+	Topics        []string `json:"topics"`
+	Fork          bool     `json:"fork"`
+	Archived      bool     `json:"archived"`
+	IsTemplate    bool     `json:"is_template"`
+	// TemplateRepository はテンプレートから作成された場合の作成元（owner/name）
+	TemplateRepository string `json:"template_repository,omitempty"`
+}
+
+// TemplateData は File の内容を描画するときに渡す値
+type TemplateData struct {
+	Owner         string
+	Repo          string
+	FullName      string
+	Year          int
+	DefaultBranch string
+	Description   string
+	Visibility    string
+	Topics        []string
+}
+
+func NewTemplateData(repo Repository, meta RepositoryMetadata, year int) TemplateData {
+	branch := meta.DefaultBranch
+	if branch == "" {
+		branch = "main"
+	}
+	return TemplateData{
+		Owner:         repo.Owner,
+		Repo:          repo.Name,
+		FullName:      repo.FullName(),
+		Year:          year,
+		DefaultBranch: branch,
+		Description:   meta.Description,
+		Visibility:    meta.Visibility,
+		Topics:        meta.Topics,
+	}
+}
+
+var templateFuncs = template.FuncMap{
+	"join":  strings.Join,
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+}
+
+func parseTemplate(name, content string) (*template.Template, error) {
+	return template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(content)
+}
+
+// Render は Content を text/template として描画した File を返す。Raw の場合はそのまま返す
+func (f File) Render(data TemplateData) (File, error) {
+	if f.Raw {
+		return f, nil
+	}
+
+	tmpl, err := parseTemplate(f.Path, f.Content)
+	if err != nil {
+		return File{}, fmt.Errorf("failed to parse template %s: %w", f.Path, err)
+	}
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return File{}, fmt.Errorf("failed to render template %s: %w", f.Path, err)
+	}
+
+	f.Content = b.String()
+	return f, nil
+}
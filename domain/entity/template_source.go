@@ -0,0 +1,139 @@
+package entity
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TemplateSource はテンプレートファイルをコピーする元のリポジトリ
+type TemplateSource struct {
+	Owner string
+	Name  string
+	// Ref はブランチ・タグ・コミット。空ならデフォルトブランチ
+	Ref string
+	// Path はコピーするディレクトリ。空ならリポジトリ全体
+	Path string
+	// Include / Exclude は Path からの相対パスに対する glob（** はディレクトリをまたぐ）。
+	// Include が空なら全てのファイルを対象にし、Exclude に一致したファイルは除く
+	Include []string
+	Exclude []string
+	// Raw が true の場合は .tmpl のファイルも描画せず、名前も変えずにそのままコピーする
+	Raw        bool
+	OnConflict ConflictPolicy
+}
+
+// TemplateSuffix の付いたファイルだけをテンプレートとして描画し、拡張子を除いたパスに作成する。
+// それ以外のファイル（${{ }} を含むワークフローなど）は内容を変えずにコピーする
+const TemplateSuffix = ".tmpl"
+
+// FullName は owner/name 形式の名前を返す
+func (s TemplateSource) FullName() string {
+	return s.Owner + "/" + s.Name
+}
+
+// Dir は Path を末尾の / を除いた形で返す
+func (s TemplateSource) Dir() string {
+	return strings.Trim(s.Path, "/")
+}
+
+// Matches は Path からの相対パス p をコピーするか判定する
+func (s TemplateSource) Matches(p string) bool {
+	if len(s.Include) > 0 && !matchAnyGlob(s.Include, p) {
+		return false
+	}
+	return !matchAnyGlob(s.Exclude, p)
+}
+
+// Files は取得したファイルを Include / Exclude で絞り込み、プロファイルのファイルとして返す。
+// 描画するのは TemplateSuffix の付いたファイルだけで、同じパスの .tmpl がある場合はそちらを優先する
+func (s TemplateSource) Files(files []File) []File {
+	rendered := make(map[string]bool)
+	if !s.Raw {
+		for _, f := range files {
+			if s.Matches(f.Path) && strings.HasSuffix(f.Path, TemplateSuffix) {
+				rendered[strings.TrimSuffix(f.Path, TemplateSuffix)] = true
+			}
+		}
+	}
+
+	selected := make([]File, 0, len(files))
+	for _, f := range files {
+		if !s.Matches(f.Path) {
+			continue
+		}
+		path, raw := f.Path, true
+		if !s.Raw && strings.HasSuffix(f.Path, TemplateSuffix) {
+			path, raw = strings.TrimSuffix(f.Path, TemplateSuffix), false
+		} else if rendered[f.Path] {
+			continue
+		}
+		selected = append(selected, File{
+			Path:       path,
+			Content:    f.Content,
+			Message:    "Add " + path + " from " + s.FullName(),
+			OnConflict: s.OnConflict,
+			Raw:        raw,
+			Mode:       f.Mode,
+		})
+	}
+	return selected
+}
+
+func (s TemplateSource) Validate() error {
+	var errs []error
+	if s.Owner == "" || s.Name == "" || strings.Contains(s.Name, "/") {
+		errs = append(errs, errors.New("template_repository.repository must be owner/name"))
+	}
+	if strings.Contains(s.Path, "..") {
+		errs = append(errs, fmt.Errorf("template_repository.path %q must not contain ..", s.Path))
+	}
+	for _, pattern := range append(append([]string{}, s.Include...), s.Exclude...) {
+		if _, err := globRegexp(pattern); err != nil {
+			errs = append(errs, fmt.Errorf("template_repository: invalid glob %q: %w", pattern, err))
+		}
+	}
+	if s.OnConflict != "" && !s.OnConflict.IsValid() {
+		errs = append(errs, fmt.Errorf("template_repository.on_conflict: unknown on_conflict %q", s.OnConflict))
+	}
+	return errors.Join(errs...)
+}
+
+func matchAnyGlob(patterns []string, p string) bool {
+	for _, pattern := range patterns {
+		re, err := globRegexp(pattern)
+		if err == nil && re.MatchString(p) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp は glob を正規表現に変換する。* と ? は / に一致せず、** は任意の階層に一致する
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		return nil, errors.New("empty pattern")
+	}
+
+	var b strings.Builder
+	b.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case strings.HasPrefix(pattern[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(pattern[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,140 @@
+package entity
+
+import (
+	"testing"
+)
+
+func TestTemplateSourceFiles(t *testing.T) {
+	workflow := "on: push\njobs:\n  build:\n    runs-on: ubuntu-latest\n    steps:\n      - run: echo ${{ github.ref }}\n"
+	files := []File{
+		{Path: ".github/workflows/ci.yml", Content: workflow},
+		{Path: "README.md.tmpl", Content: "# {{.Repo}}\n"},
+		{Path: "README.md", Content: "# plain\n"},
+		{Path: "docs/{{raw}}.md", Content: "{{ not a template }}"},
+	}
+	source := TemplateSource{Owner: "org", Name: "templates"}
+	profile := &SetupProfile{TemplateSource: &source}
+	data := NewTemplateData(Repository{Owner: "org", Name: "app"}, RepositoryMetadata{}, 2026)
+
+	rendered, err := profile.TemplateFiles(data, source.Files(files))
+	if err != nil {
+		t.Fatalf("TemplateFiles() error = %v", err)
+	}
+
+	got := make(map[string]string)
+	for _, f := range rendered {
+		got[f.GetPath()] = f.GetContent()
+	}
+	want := map[string]string{
+		".github/workflows/ci.yml": workflow,
+		"README.md":                "# app\n",
+		"docs/{{raw}}.md":          "{{ not a template }}",
+	}
+	if len(got) != len(want) {
+		t.Fatalf("files = %v, want %v", got, want)
+	}
+	for path, content := range want {
+		if got[path] != content {
+			t.Errorf("%s = %q, want %q", path, got[path], content)
+		}
+	}
+}
+
+func TestTemplateSourceFilesRaw(t *testing.T) {
+	source := TemplateSource{Owner: "org", Name: "templates", Raw: true}
+	files := source.Files([]File{{Path: "README.md.tmpl", Content: "# {{.Repo}}\n"}})
+
+	if len(files) != 1 || files[0].Path != "README.md.tmpl" || !files[0].Raw {
+		t.Fatalf("Files() = %+v, want README.md.tmpl copied as is", files)
+	}
+}
+
+func TestTemplateSourceFilesKeepsMode(t *testing.T) {
+	source := TemplateSource{Owner: "org", Name: "templates"}
+	files := source.Files([]File{
+		{Path: "scripts/setup.sh", Content: "#!/bin/sh\n", Mode: FileModeExecutable},
+		{Path: "scripts/run.sh.tmpl", Content: "#!/bin/sh\necho {{.Repo}}\n", Mode: FileModeExecutable},
+		{Path: "README.md", Content: "# readme\n"},
+	})
+
+	want := map[string]string{
+		"scripts/setup.sh": FileModeExecutable,
+		"scripts/run.sh":   FileModeExecutable,
+		"README.md":        FileModeRegular,
+	}
+	for _, f := range files {
+		if f.GetMode() != want[f.Path] {
+			t.Errorf("%s mode = %s, want %s", f.Path, f.GetMode(), want[f.Path])
+		}
+	}
+	if len(files) != len(want) {
+		t.Errorf("Files() returned %d files, want %d", len(files), len(want))
+	}
+}
+
+func TestGlobRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.md", "README.md", true},
+		{"*.md", "docs/README.md", false},
+		{"docs/*", "docs/intro.md", true},
+		{"docs/*", "docs/guide/intro.md", false},
+		{"docs/**", "docs/guide/intro.md", true},
+		{"docs/**", "docs", false},
+		{"**/*.yml", "ci.yml", true},
+		{"**/*.yml", ".github/workflows/ci.yml", true},
+		{"**/*.yml", ".github/workflows/ci.yaml", false},
+		{".github/**/*.yml", ".github/ci.yml", true},
+		{".github/**/*.yml", ".github/workflows/nested/ci.yml", true},
+		{"?.txt", "a.txt", true},
+		{"?.txt", "ab.txt", false},
+		{"?.txt", "/.txt", false},
+		{"file.txt", "fileatxt", false},
+		{"a+b(1).md", "a+b(1).md", true},
+		{"[abc].md", "a.md", false},
+		{"[abc].md", "[abc].md", true},
+	}
+
+	for _, tt := range tests {
+		re, err := globRegexp(tt.pattern)
+		if err != nil {
+			t.Fatalf("globRegexp(%q) error = %v", tt.pattern, err)
+		}
+		if got := re.MatchString(tt.path); got != tt.want {
+			t.Errorf("glob %q match %q = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+
+	if _, err := globRegexp(""); err == nil {
+		t.Error("globRegexp(\"\") error = nil")
+	}
+}
+
+func TestTemplateSourceMatches(t *testing.T) {
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		path    string
+		want    bool
+	}{
+		{name: "no filters", path: "README.md", want: true},
+		{name: "included", include: []string{".github/**"}, path: ".github/workflows/ci.yml", want: true},
+		{name: "not included", include: []string{".github/**"}, path: "README.md", want: false},
+		{name: "any include", include: []string{"*.md", ".github/**"}, path: "README.md", want: true},
+		{name: "excluded", exclude: []string{"**/*.tmp"}, path: "docs/a.tmp", want: false},
+		{name: "exclude wins", include: []string{"docs/**"}, exclude: []string{"docs/drafts/**"}, path: "docs/drafts/a.md", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := TemplateSource{Owner: "org", Name: "templates", Include: tt.include, Exclude: tt.exclude}
+			if got := source.Matches(tt.path); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
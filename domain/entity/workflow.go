@@ -1,35 +1,137 @@
 package entity
 
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// ConflictPolicy は作成するファイルが既に存在する場合の扱い
+type ConflictPolicy string
+
+const (
+	// ConflictSkip は既存のファイルを残して作成しない
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite は既存のファイルを上書きする
+	ConflictOverwrite ConflictPolicy = "overwrite"
+	// ConflictFail はセットアップをエラーで中断する
+	ConflictFail ConflictPolicy = "fail"
+	// ConflictWriteAlongside は既存のファイルを残し、AlongsidePath に作成する
+	ConflictWriteAlongside ConflictPolicy = "write_alongside"
+)
+
+func (p ConflictPolicy) IsValid() bool {
+	switch p {
+	case ConflictSkip, ConflictOverwrite, ConflictFail, ConflictWriteAlongside:
+		return true
+	}
+	return false
+}
+
+// AlongsidePath は write_alongside で作成するファイルのパスを返す
+// （例: LICENSE → LICENSE.template, CONTRIBUTING.md → CONTRIBUTING.template.md）
+func AlongsidePath(p string) string {
+	dir, base := path.Split(p)
+	ext := path.Ext(base)
+	if ext == base {
+		// .gitignore のような拡張子のみのファイル名
+		ext = ""
+	}
+	return dir + strings.TrimSuffix(base, ext) + ".template" + ext
+}
+
 // FileContent はファイルの内容を表すインターフェース
 type FileContent interface {
 	GetPath() string
 	GetContent() string
 	GetMessage() string
+	GetConflictPolicy() ConflictPolicy
+	// GetMode は Git のファイルモード（FileModeRegular / FileModeExecutable）
+	GetMode() string
 }
 
+// Git のファイルモード
+const (
+	FileModeRegular    = "100644"
+	FileModeExecutable = "100755"
+)
+
 type Workflow struct {
-	Path    string
-	Content string
-	Message string
+	Path       string
+	Content    string
+	Message    string
+	OnConflict ConflictPolicy
 }
 
 func (w Workflow) GetPath() string    { return w.Path }
 func (w Workflow) GetContent() string { return w.Content }
 func (w Workflow) GetMessage() string { return w.Message }
+func (w Workflow) GetMode() string    { return FileModeRegular }
+
+// GetConflictPolicy は未指定の場合 overwrite を返す（ワークフローはこのアプリが管理する）
+func (w Workflow) GetConflictPolicy() ConflictPolicy {
+	if w.OnConflict == "" {
+		return ConflictOverwrite
+	}
+	return w.OnConflict
+}
 
+// File はプロファイルで作成するファイル。Content は text/template として描画される
 type File struct {
-	Path    string
-	Content string
-	Message string
+	Path       string
+	Content    string
+	Message    string
+	OnConflict ConflictPolicy
+	// Raw が true の場合は Content を描画せずにそのまま作成する
+	Raw bool
+	// Mode は Git のファイルモード。空なら FileModeRegular（テンプレートリポジトリの実行ファイルは FileModeExecutable）
+	Mode string
 }
 
 func (f File) GetPath() string    { return f.Path }
 func (f File) GetContent() string { return f.Content }
 func (f File) GetMessage() string { return f.Message }
 
+func (f File) GetMode() string {
+	if f.Mode == "" {
+		return FileModeRegular
+	}
+	return f.Mode
+}
+
+// GetConflictPolicy は未指定の場合 skip を返す（利用者のファイルを壊さない）
+func (f File) GetConflictPolicy() ConflictPolicy {
+	if f.OnConflict == "" {
+		return ConflictSkip
+	}
+	return f.OnConflict
+}
+
+// SetupLabelsWorkflowPath はラベル設定ワークフローの配置先
+const SetupLabelsWorkflowPath = ".github/workflows/setup-labels.yml"
+
 func DefaultSetupLabelsWorkflow() Workflow {
+	return SetupLabelsWorkflow(DefaultLabels())
+}
+
+// SetupLabelsWorkflow は指定したラベルを作成するワークフローを生成する
+func SetupLabelsWorkflow(labels []Label) Workflow {
+	// bash のダブルクォート内で展開されないようにエスケープ
+	escape := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "$", `\$`, "`", "\\`").Replace
+
+	var entries strings.Builder
+	for _, l := range labels {
+		fmt.Fprintf(&entries, "            \"%s|%s|%s\"\n", escape(l.Name), l.Color, escape(l.Description))
+	}
+
+	// 最後のラベルが既にあればセットアップ済みとみなす
+	marker := ""
+	if len(labels) > 0 {
+		marker = labels[len(labels)-1].Name
+	}
+
 	return Workflow{
-		Path:    ".github/workflows/setup-labels.yml",
+		Path:    SetupLabelsWorkflowPath,
 		Message: "Add setup-labels workflow",
 		Content: `name: setup-labels
 
@@ -52,7 +154,7 @@ jobs:
       - name: Check if already setup
         id: check
         run: |
-          if gh label list --repo ${{ github.repository }} --json name --jq '.[].name' | grep -q "^refactor$"; then
+          if gh label list --repo ${{ github.repository }} --json name --jq '.[].name' | grep -qxF "` + escape(marker) + `"; then
             echo "skip=true" >> $GITHUB_OUTPUT
           else
             echo "skip=false" >> $GITHUB_OUTPUT
@@ -73,12 +175,7 @@ jobs:
         if: steps.check.outputs.skip == 'false'
         run: |
           labels=(
-            "bug|d73a4a|バグ報告"
-            "feature|a2eeef|新機能追加"
-            "docs|0075ca|ドキュメント改善"
-            "refactor|fbca04|リファクタリング"
-            "other|5319e7|その他"
-          )
+` + entries.String() + `          )
 
           for label in "${labels[@]}"; do
             IFS='|' read -r name color description <<< "$label"
@@ -90,45 +187,16 @@ jobs:
 	}
 }
 
-func DefaultLicenseFile() File {
-	return File{
-		Path:    "LICENSE",
-		Message: "Add LICENSE file",
-		Content: `MIT License
-
-Copyright (c) [2025] [rikut0904]
-
-Permission is hereby granted, free of charge, to any person obtaining a copy
-of this software and associated documentation files (the "Software"), to deal
-in the Software without restriction, including without limitation the rights
-to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
-copies of the Software, and to permit persons to whom the Software is
-furnished to do so, subject to the following conditions:
-
-The above copyright notice and this permission notice shall be included in all
-copies or substantial portions of the Software.
-
-THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
-IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
-FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
-AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
-LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
-OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN THE
-SOFTWARE.
-`,
-	}
-}
-
 func DefaultContributingFile() File {
 	return File{
 		Path:    "CONTRIBUTING.md",
 		Message: "Add CONTRIBUTING.md file",
-		Content: `# コントリビューションガイド
+		Content: `# {{.Repo}} へのコントリビューションガイド
 
 ## 開発フロー
 
 1. Issueを作成または既存のIssueを確認
-2. ` + "`main`" + `ブランチから作業ブランチを作成
+2. ` + "`{{.DefaultBranch}}`" + `ブランチから作業ブランチを作成
 3. 変更を実装
 4. Pull Requestを作成
 
@@ -166,3 +234,28 @@ other/ビルド・設定変更
 `,
 	}
 }
+
+func DefaultReadmeFile() File {
+	return File{
+		Path:    "README.md",
+		Message: "Add README.md file",
+		Content: `# {{.Repo}}
+{{- if .Description}}
+
+{{.Description}}
+{{- end}}
+{{- if .Topics}}
+
+Topics: {{join .Topics ", "}}
+{{- end}}
+
+## コントリビューション
+
+[CONTRIBUTING.md](./CONTRIBUTING.md) を参照してください。
+
+## ライセンス
+
+[LICENSE](./LICENSE) を参照してください。
+`,
+	}
+}
@@ -0,0 +1,14 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// DeliveryStore は処理済みの Webhook 配信 ID（X-GitHub-Delivery）を一定期間記録する
+type DeliveryStore interface {
+	// MarkSeen は配信 ID を記録する。期限内に記録済みの場合は false を返す
+	MarkSeen(ctx context.Context, id string, now time.Time) (bool, error)
+	// Forget は記録を取り消す（処理に失敗した配信を再配信で受け付けるため）
+	Forget(ctx context.Context, id string) error
+}
@@ -7,8 +7,29 @@ import (
 )
 
 type GitHubRepository interface {
+	// GetRepositoryMetadata はテンプレートの描画やプロファイルの選択に使うリポジトリの情報を返す
+	GetRepositoryMetadata(ctx context.Context, repo entity.Repository) (entity.RepositoryMetadata, error)
+	// GetFileContent はデフォルトブランチのファイル内容を返す。存在しない場合 exists は false
+	GetFileContent(ctx context.Context, repo entity.Repository, path string) (content string, exists bool, err error)
+	// ListDirectoryFiles は ref（空ならデフォルトブランチ）の dir 以下のファイルを dir からの相対パスで返す。
+	// match が nil でなければ、相対パスが一致したファイルの内容だけを取得する
+	ListDirectoryFiles(ctx context.Context, repo entity.Repository, ref, dir string, match func(path string) bool) ([]entity.File, error)
 	CreateFile(ctx context.Context, repo entity.Repository, file entity.FileContent) error
 	CreateFiles(ctx context.Context, repo entity.Repository, files []entity.FileContent, commitMessage string) error
+	// DeleteWorkflowFile は path のファイルを削除する。既に削除されていればエラーにしない
 	DeleteWorkflowFile(ctx context.Context, repo entity.Repository, path string) error
 	CreateSecret(ctx context.Context, repo entity.Repository, secretName, secretValue string) error
+	ListLabels(ctx context.Context, repo entity.Repository) ([]entity.Label, error)
+	CreateLabel(ctx context.Context, repo entity.Repository, label entity.Label) error
+	UpdateLabel(ctx context.Context, repo entity.Repository, name string, label entity.Label) error
+	DeleteLabel(ctx context.Context, repo entity.Repository, name string) error
+	// FindRepositoryInstallation は App がインストールされている owner/name の installation ID を返す
+	FindRepositoryInstallation(ctx context.Context, owner, name string) (int64, error)
+	ListInstallationRepositories(ctx context.Context, installationID int64) ([]entity.Repository, error)
+}
+
+// GitHubRecorder は変更を GitHub に送らずに記録する GitHubRepository（ドライラン）
+type GitHubRecorder interface {
+	GitHubRepository
+	Actions() []entity.PlannedAction
 }
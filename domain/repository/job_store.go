@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github-setup-app/domain/entity"
+)
+
+// JobStore はジョブの永続化を行う。
+// 完了したジョブは Delete で削除されるまで残るため、実行中にプロセスが終了しても失われない。
+type JobStore interface {
+	Enqueue(ctx context.Context, job *entity.Job) error
+	// Claim は now 時点で実行可能な pending のジョブを1件 running にし、試行回数を1増やして返す。なければ nil。
+	// 実行前に試行回数を保存するため、実行中にプロセスが終了した場合も1回の試行として数える
+	Claim(ctx context.Context, now time.Time) (*entity.Job, error)
+	// Save は既存のジョブを更新する。削除済みのジョブは保存しない
+	Save(ctx context.Context, job *entity.Job) error
+	Delete(ctx context.Context, id string) error
+	// DeleteByInstallation は installation のジョブを全て削除し、その件数を返す
+	DeleteByInstallation(ctx context.Context, installationID int64) (int, error)
+	// DeleteFailed は before より前に failed になったジョブを削除し、その件数を返す
+	DeleteFailed(ctx context.Context, before time.Time) (int, error)
+	// ResetRunning は running のまま残ったジョブを pending に戻し、その件数を返す
+	ResetRunning(ctx context.Context) (int, error)
+	// CountByStatus はステータスごとのジョブの件数を返す
+	CountByStatus(ctx context.Context) (map[entity.JobStatus]int, error)
+}
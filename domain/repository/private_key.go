@@ -0,0 +1,7 @@
+package repository
+
+// PrivateKeyProvider は GitHub App の秘密鍵（PEM）を返す。
+// 鍵は実行中に入れ替わることがあるため、保持せずに使うたびに呼び出す
+type PrivateKeyProvider interface {
+	PrivateKey() []byte
+}
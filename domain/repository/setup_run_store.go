@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github-setup-app/domain/entity"
+)
+
+// SetupRunStore はリポジトリごとに最新のセットアップ実行記録を保存する
+type SetupRunStore interface {
+	Save(ctx context.Context, run *entity.SetupRun) error
+	// Get は owner/name の最新の実行記録を返す。なければ nil
+	Get(ctx context.Context, owner, name string) (*entity.SetupRun, error)
+	List(ctx context.Context) ([]entity.SetupRun, error)
+	// DeleteByInstallation は installation の実行記録を全て削除し、その件数を返す
+	DeleteByInstallation(ctx context.Context, installationID int64) (int, error)
+}
@@ -0,0 +1,36 @@
+package github
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	jwt "github.com/golang-jwt/jwt/v4"
+)
+
+// MintAppJWT は App として認証する JWT を privateKey で署名して作成する。
+// API は呼び出さないため、鍵と App ID が使えるかの確認に使う
+func MintAppJWT(appID int64, privateKey []byte, now time.Time) (string, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse private key: %w", err)
+	}
+
+	// GitHub の時計とのずれを考慮して iat を過去にする（ghinstallation と同じ）
+	claims := &jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now.Add(-30 * time.Second)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		Issuer:    strconv.FormatInt(appID, 10),
+	}
+	token, err := ghinstallation.NewRSASigner(jwt.SigningMethodRS256, key).Sign(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign app JWT: %w", err)
+	}
+	return token, nil
+}
+
+// AppJWT はこのクライアントの App ID と秘密鍵で JWT を作成する
+func (c *GitHubClient) AppJWT(now time.Time) (string, error) {
+	return MintAppJWT(c.appID, c.privateKey.PrivateKey(), now)
+}
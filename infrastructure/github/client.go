@@ -6,42 +6,103 @@ import (
 	"encoding/base64"
 	"fmt"
 	"net/http"
+	"strings"
 
-	"github.com/bradleyfalzon/ghinstallation/v2"
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/crypto/blake2b"
 	"golang.org/x/crypto/nacl/box"
 
 	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+	"github-setup-app/tracing"
 )
 
 type GitHubClient struct {
-	appID      int64
-	privateKey []byte
+	appID       int64
+	privateKey  repository.PrivateKeyProvider
+	retryPolicy RetryPolicy
+	cacheSize   int
+
+	clients   *clientCache
+	appClient *appClientCache
+}
+
+// Option は GitHubClient の設定を変更する
+type Option func(*GitHubClient)
+
+// WithRetryPolicy は API 呼び出しの再試行方針を設定する
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *GitHubClient) {
+		c.retryPolicy = policy
+	}
 }
 
-func NewGitHubClient(appID int64, privateKey []byte) *GitHubClient {
-	return &GitHubClient{
-		appID:      appID,
-		privateKey: privateKey,
+// WithClientCacheSize はキャッシュするインストールごとのクライアントの上限を設定する。0 以下なら DefaultClientCacheSize を使う
+func WithClientCacheSize(n int) Option {
+	return func(c *GitHubClient) {
+		if n > 0 {
+			c.cacheSize = n
+		}
 	}
 }
 
+// NewGitHubClient は GitHubClient を作成する。秘密鍵は API を呼び出すたびに privateKey から取得する
+func NewGitHubClient(appID int64, privateKey repository.PrivateKeyProvider, opts ...Option) *GitHubClient {
+	c := &GitHubClient{
+		appID:       appID,
+		privateKey:  privateKey,
+		retryPolicy: DefaultRetryPolicy(),
+		cacheSize:   DefaultClientCacheSize,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	c.clients = newClientCache(c.cacheSize, c.newInstallationClient)
+	c.appClient = &appClientCache{newClient: c.newAppClient}
+	return c
+}
+
+// getClient はインストールごとにキャッシュしたクライアントを返す。秘密鍵が入れ替わっていれば作り直す
 func (c *GitHubClient) getClient(installationID int64) (*github.Client, error) {
-	itr, err := ghinstallation.New(
-		http.DefaultTransport,
-		c.appID,
-		installationID,
-		c.privateKey,
-	)
+	return c.clients.get(installationID, c.privateKey.PrivateKey())
+}
+
+// getAppClient は App 自身（JWT）として認証するクライアントを返す
+func (c *GitHubClient) getAppClient() (*github.Client, error) {
+	return c.appClient.get(c.privateKey.PrivateKey())
+}
+
+func (c *GitHubClient) GetFileContent(ctx context.Context, repo entity.Repository, path string) (_ string, _ bool, err error) {
+	ctx, span := startSpan(ctx, "GetFileContent")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return "", false, err
+	}
+
+	fileContent, _, resp, err := client.Repositories.GetContents(expectNotFound(ctx), repo.Owner, repo.Name, path, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create installation transport: %w", err)
+		// 空のリポジトリやファイルがない場合は 404
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return "", false, nil
+		}
+		return "", false, fmt.Errorf("failed to get file %s: %w", path, err)
+	}
+	if fileContent == nil {
+		return "", true, fmt.Errorf("%s is a directory", path)
 	}
 
-	return github.NewClient(&http.Client{Transport: itr}), nil
+	content, err := fileContent.GetContent()
+	if err != nil {
+		return "", true, fmt.Errorf("failed to decode file %s: %w", path, err)
+	}
+
+	return content, true, nil
 }
 
-func (c *GitHubClient) CreateFile(ctx context.Context, repo entity.Repository, file entity.FileContent) error {
+func (c *GitHubClient) CreateFile(ctx context.Context, repo entity.Repository, file entity.FileContent) (err error) {
+	ctx, span := startSpan(ctx, "CreateFile")
+	defer func() { tracing.End(span, err) }()
 	client, err := c.getClient(repo.InstallationID)
 	if err != nil {
 		return err
@@ -55,27 +116,192 @@ func (c *GitHubClient) CreateFile(ctx context.Context, repo entity.Repository, f
 	return err
 }
 
-func (c *GitHubClient) CreateFiles(ctx context.Context, repo entity.Repository, files []entity.FileContent, commitMessage string) error {
-	// 高レベルAPIで各ファイルを個別に作成
+// ListDirectoryFiles は Git Trees API でツリーを再帰的に取得し、dir 以下の通常ファイルの内容とモードを返す。
+// シンボリックリンクとサブモジュールは含めない。match に一致しないファイルは blob を取得しない
+func (c *GitHubClient) ListDirectoryFiles(ctx context.Context, repo entity.Repository, ref, dir string, match func(path string) bool) (_ []entity.File, err error) {
+	ctx, span := startSpan(ctx, "ListDirectoryFiles")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	if ref == "" {
+		ghRepo, _, err := client.Repositories.Get(ctx, repo.Owner, repo.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get repository: %w", err)
+		}
+		ref = ghRepo.GetDefaultBranch()
+	}
+
+	tree, _, err := client.Git.GetTree(ctx, repo.Owner, repo.Name, ref, true)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tree %s of %s/%s: %w", ref, repo.Owner, repo.Name, err)
+	}
+	if tree.GetTruncated() {
+		return nil, fmt.Errorf("tree %s of %s/%s is too large", ref, repo.Owner, repo.Name)
+	}
+
+	prefix := ""
+	if dir = strings.Trim(dir, "/"); dir != "" {
+		prefix = dir + "/"
+	}
+
+	var files []entity.File
+	for _, entry := range tree.Entries {
+		mode := entry.GetMode()
+		if entry.GetType() != "blob" || (mode != entity.FileModeRegular && mode != entity.FileModeExecutable) || !strings.HasPrefix(entry.GetPath(), prefix) {
+			continue
+		}
+		path := strings.TrimPrefix(entry.GetPath(), prefix)
+		if match != nil && !match(path) {
+			continue
+		}
+		content, _, err := client.Git.GetBlobRaw(ctx, repo.Owner, repo.Name, entry.GetSHA())
+		if err != nil {
+			return nil, fmt.Errorf("failed to get blob for %s: %w", entry.GetPath(), err)
+		}
+		files = append(files, entity.File{
+			Path:    path,
+			Content: string(content),
+			Mode:    mode,
+		})
+	}
+
+	return files, nil
+}
+
+// CreateFiles は Git Data API（blob / tree / commit / ref）で全ファイルを1コミットにまとめて作成する
+func (c *GitHubClient) CreateFiles(ctx context.Context, repo entity.Repository, files []entity.FileContent, commitMessage string) (err error) {
+	ctx, span := startSpan(ctx, "CreateFiles")
+	defer func() { tracing.End(span, err) }()
+	if len(files) == 0 {
+		return nil
+	}
+
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	ghRepo, _, err := client.Repositories.Get(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return fmt.Errorf("failed to get repository: %w", err)
+	}
+	branch := ghRepo.GetDefaultBranch()
+	if branch == "" {
+		branch = "main"
+	}
+
+	ref, empty, err := c.getBranchRef(ctx, client, repo, branch)
+	if err != nil {
+		return err
+	}
+
+	// Git Data API は空のリポジトリでは使えないため、Contents API で初期コミットを作る。
+	// このコミットは後で ref を強制更新して置き換えるので履歴には残らない。
+	if empty {
+		_, _, err = client.Repositories.CreateFile(ctx, repo.Owner, repo.Name, files[0].GetPath(), &github.RepositoryContentFileOptions{
+			Message: github.String(commitMessage),
+			Content: []byte(files[0].GetContent()),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to bootstrap empty repository: %w", err)
+		}
+
+		var stillEmpty bool
+		ref, stillEmpty, err = c.getBranchRef(ctx, client, repo, branch)
+		if err != nil {
+			return err
+		}
+		if stillEmpty {
+			return fmt.Errorf("branch %s not found after bootstrapping empty repository", branch)
+		}
+	}
+
+	entries := make([]*github.TreeEntry, 0, len(files))
 	for _, file := range files {
-		if err := c.CreateFile(ctx, repo, file); err != nil {
-			return fmt.Errorf("failed to create file %s: %w", file.GetPath(), err)
+		blob, _, err := client.Git.CreateBlob(ctx, repo.Owner, repo.Name, &github.Blob{
+			Content:  github.String(base64.StdEncoding.EncodeToString([]byte(file.GetContent()))),
+			Encoding: github.String("base64"),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create blob for %s: %w", file.GetPath(), err)
+		}
+		entries = append(entries, &github.TreeEntry{
+			Path: github.String(file.GetPath()),
+			Mode: github.String(file.GetMode()),
+			Type: github.String("blob"),
+			SHA:  blob.SHA,
+		})
+	}
+
+	// 空のリポジトリでは親を持たないルートコミットとして作成する
+	var baseTree string
+	var parents []*github.Commit
+	if !empty {
+		parent, _, err := client.Git.GetCommit(ctx, repo.Owner, repo.Name, ref.GetObject().GetSHA())
+		if err != nil {
+			return fmt.Errorf("failed to get parent commit: %w", err)
 		}
+		baseTree = parent.GetTree().GetSHA()
+		parents = []*github.Commit{{SHA: parent.SHA}}
+	}
+
+	tree, _, err := client.Git.CreateTree(ctx, repo.Owner, repo.Name, baseTree, entries)
+	if err != nil {
+		return fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	commit, _, err := client.Git.CreateCommit(ctx, repo.Owner, repo.Name, &github.Commit{
+		Message: github.String(commitMessage),
+		Tree:    &github.Tree{SHA: tree.SHA},
+		Parents: parents,
+	}, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create commit: %w", err)
 	}
+
+	ref.Object = &github.GitObject{SHA: commit.SHA}
+	if _, _, err := client.Git.UpdateRef(ctx, repo.Owner, repo.Name, ref, empty); err != nil {
+		return fmt.Errorf("failed to update ref: %w", err)
+	}
+
 	return nil
 }
 
-func (c *GitHubClient) DeleteWorkflowFile(ctx context.Context, repo entity.Repository, path string) error {
+// getBranchRef はブランチの ref を取得する。リポジトリが空の場合は empty を true で返す
+func (c *GitHubClient) getBranchRef(ctx context.Context, client *github.Client, repo entity.Repository, branch string) (ref *github.Reference, empty bool, err error) {
+	ref, resp, err := client.Git.GetRef(expectNotFound(ctx), repo.Owner, repo.Name, "heads/"+branch)
+	if err != nil {
+		// 空のリポジトリは 409 (Git Repository is empty)、ブランチ未作成は 404 が返る
+		if resp != nil && (resp.StatusCode == http.StatusConflict || resp.StatusCode == http.StatusNotFound) {
+			return nil, true, nil
+		}
+		return nil, false, fmt.Errorf("failed to get ref: %w", err)
+	}
+	return ref, false, nil
+}
+
+func (c *GitHubClient) DeleteWorkflowFile(ctx context.Context, repo entity.Repository, path string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteWorkflowFile")
+	defer func() { tracing.End(span, err) }()
 	client, err := c.getClient(repo.InstallationID)
 	if err != nil {
 		return err
 	}
 
-	// ファイルの SHA を取得
-	fileContent, _, _, err := client.Repositories.GetContents(ctx, repo.Owner, repo.Name, path, nil)
+	// ファイルの SHA を取得。ジョブの再実行などで削除済みなら何もしない
+	fileContent, _, resp, err := client.Repositories.GetContents(expectNotFound(ctx), repo.Owner, repo.Name, path, nil)
 	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
 		return fmt.Errorf("failed to get file: %w", err)
 	}
+	if fileContent == nil {
+		return fmt.Errorf("%s is a directory", path)
+	}
 
 	// ファイルを削除
 	_, _, err = client.Repositories.DeleteFile(ctx, repo.Owner, repo.Name, path, &github.RepositoryContentFileOptions{
@@ -86,7 +312,9 @@ func (c *GitHubClient) DeleteWorkflowFile(ctx context.Context, repo entity.Repos
 	return err
 }
 
-func (c *GitHubClient) CreateSecret(ctx context.Context, repo entity.Repository, secretName, secretValue string) error {
+func (c *GitHubClient) CreateSecret(ctx context.Context, repo entity.Repository, secretName, secretValue string) (err error) {
+	ctx, span := startSpan(ctx, "CreateSecret")
+	defer func() { tracing.End(span, err) }()
 	client, err := c.getClient(repo.InstallationID)
 	if err != nil {
 		return err
@@ -119,6 +347,179 @@ func (c *GitHubClient) CreateSecret(ctx context.Context, repo entity.Repository,
 	return nil
 }
 
+func (c *GitHubClient) ListLabels(ctx context.Context, repo entity.Repository) (_ []entity.Label, err error) {
+	ctx, span := startSpan(ctx, "ListLabels")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var labels []entity.Label
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Issues.ListLabels(ctx, repo.Owner, repo.Name, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list labels: %w", err)
+		}
+		for _, l := range page {
+			labels = append(labels, entity.Label{
+				Name:        l.GetName(),
+				Color:       l.GetColor(),
+				Description: l.GetDescription(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return labels, nil
+}
+
+func (c *GitHubClient) CreateLabel(ctx context.Context, repo entity.Repository, label entity.Label) (err error) {
+	ctx, span := startSpan(ctx, "CreateLabel")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = client.Issues.CreateLabel(ctx, repo.Owner, repo.Name, &github.Label{
+		Name:        github.String(label.Name),
+		Color:       github.String(label.Color),
+		Description: github.String(label.Description),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create label %s: %w", label.Name, err)
+	}
+
+	return nil
+}
+
+// UpdateLabel は name のラベルを label の内容に更新する（名前の変更も可能）
+func (c *GitHubClient) UpdateLabel(ctx context.Context, repo entity.Repository, name string, label entity.Label) (err error) {
+	ctx, span := startSpan(ctx, "UpdateLabel")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	_, _, err = client.Issues.EditLabel(ctx, repo.Owner, repo.Name, name, &github.Label{
+		Name:        github.String(label.Name),
+		Color:       github.String(label.Color),
+		Description: github.String(label.Description),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update label %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (c *GitHubClient) DeleteLabel(ctx context.Context, repo entity.Repository, name string) (err error) {
+	ctx, span := startSpan(ctx, "DeleteLabel")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.Issues.DeleteLabel(ctx, repo.Owner, repo.Name, name); err != nil {
+		return fmt.Errorf("failed to delete label %s: %w", name, err)
+	}
+
+	return nil
+}
+
+func (c *GitHubClient) GetRepositoryMetadata(ctx context.Context, repo entity.Repository) (_ entity.RepositoryMetadata, err error) {
+	ctx, span := startSpan(ctx, "GetRepositoryMetadata")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(repo.InstallationID)
+	if err != nil {
+		return entity.RepositoryMetadata{}, err
+	}
+
+	ghRepo, _, err := client.Repositories.Get(ctx, repo.Owner, repo.Name)
+	if err != nil {
+		return entity.RepositoryMetadata{}, fmt.Errorf("failed to get repository %s/%s: %w", repo.Owner, repo.Name, err)
+	}
+
+	return repositoryMetadata(ghRepo), nil
+}
+
+// repositoryMetadata は API のレスポンスから RepositoryMetadata を作る。
+// visibility を返さない API もあるため private から補う
+func repositoryMetadata(ghRepo *github.Repository) entity.RepositoryMetadata {
+	visibility := ghRepo.GetVisibility()
+	if visibility == "" {
+		visibility = "public"
+		if ghRepo.GetPrivate() {
+			visibility = "private"
+		}
+	}
+	return entity.RepositoryMetadata{
+		DefaultBranch:      ghRepo.GetDefaultBranch(),
+		Description:        ghRepo.GetDescription(),
+		Visibility:         visibility,
+		Topics:             ghRepo.Topics,
+		Fork:               ghRepo.GetFork(),
+		Archived:           ghRepo.GetArchived(),
+		IsTemplate:         ghRepo.GetIsTemplate(),
+		TemplateRepository: ghRepo.GetTemplateRepository().GetFullName(),
+	}
+}
+
+func (c *GitHubClient) FindRepositoryInstallation(ctx context.Context, owner, name string) (_ int64, err error) {
+	ctx, span := startSpan(ctx, "FindRepositoryInstallation")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getAppClient()
+	if err != nil {
+		return 0, err
+	}
+
+	installation, _, err := client.Apps.FindRepositoryInstallation(expectNotFound(ctx), owner, name)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find installation for %s/%s: %w", owner, name, err)
+	}
+
+	return installation.GetID(), nil
+}
+
+func (c *GitHubClient) ListInstallationRepositories(ctx context.Context, installationID int64) (_ []entity.Repository, err error) {
+	ctx, span := startSpan(ctx, "ListInstallationRepositories")
+	defer func() { tracing.End(span, err) }()
+	client, err := c.getClient(installationID)
+	if err != nil {
+		return nil, err
+	}
+
+	var repos []entity.Repository
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		page, resp, err := client.Apps.ListRepos(ctx, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list installation repositories: %w", err)
+		}
+		for _, r := range page.Repositories {
+			repos = append(repos, entity.Repository{
+				Owner:          r.GetOwner().GetLogin(),
+				Name:           r.GetName(),
+				InstallationID: installationID,
+				Archived:       r.GetArchived(),
+			})
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return repos, nil
+}
+
 // encryptSecret は libsodium sealed box を使ってシークレットを暗号化
 func encryptSecret(publicKeyStr, secret string) (string, error) {
 	publicKeyBytes, err := base64.StdEncoding.DecodeString(publicKeyStr)
@@ -0,0 +1,159 @@
+package github
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+	"github.com/google/go-github/v57/github"
+
+	"github-setup-app/metrics"
+	"github-setup-app/tracing"
+)
+
+// DefaultClientCacheSize はキャッシュするインストールごとのクライアントの既定の上限
+const DefaultClientCacheSize = 1000
+
+// clientCache はインストールごとのクライアントを保持する。
+// ghinstallation.Transport はインストールトークンを有効期限の1分前まで使い回すため、
+// 同じインストールへの呼び出しでは JWT の発行とトークンの取得を繰り返さない。
+// 上限を超えた場合は最も長く使っていないものから追い出す
+type clientCache struct {
+	maxEntries int
+	newClient  func(installationID int64, key []byte) (*github.Client, *ghinstallation.Transport, error)
+
+	mu      sync.Mutex
+	order   *list.List // 先頭ほど最近使った *cachedClient
+	entries map[int64]*list.Element
+}
+
+type cachedClient struct {
+	installationID int64
+	// key はクライアントを作成した秘密鍵。鍵が入れ替わったら作り直す
+	key       []byte
+	client    *github.Client
+	transport *ghinstallation.Transport
+}
+
+func newClientCache(maxEntries int, newClient func(installationID int64, key []byte) (*github.Client, *ghinstallation.Transport, error)) *clientCache {
+	return &clientCache{
+		maxEntries: maxEntries,
+		newClient:  newClient,
+		order:      list.New(),
+		entries:    make(map[int64]*list.Element),
+	}
+}
+
+// get は installationID のクライアントを返す。ないか、key と異なる鍵で作成したものなら作り直す
+func (c *clientCache) get(installationID int64, key []byte) (*github.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := "miss"
+	if elem, ok := c.entries[installationID]; ok {
+		entry := elem.Value.(*cachedClient)
+		if bytes.Equal(entry.key, key) {
+			c.order.MoveToFront(elem)
+			metrics.ObserveClientCache("hit")
+			return entry.client, nil
+		}
+		c.remove(elem)
+		result = "key_rotated"
+	}
+	metrics.ObserveClientCache(result)
+
+	client, transport, err := c.newClient(installationID, key)
+	if err != nil {
+		return nil, err
+	}
+	c.entries[installationID] = c.order.PushFront(&cachedClient{
+		installationID: installationID,
+		key:            key,
+		client:         client,
+		transport:      transport,
+	})
+	c.evict()
+	return client, nil
+}
+
+// evict はトークンが期限切れで使われていないものと、上限を超えた分を古い順に追い出す
+func (c *clientCache) evict() {
+	now := time.Now()
+	for elem := c.order.Back(); elem != nil && elem != c.order.Front(); elem = c.order.Back() {
+		switch {
+		case c.order.Len() > c.maxEntries:
+			metrics.ObserveClientCacheEviction("capacity")
+		case c.expired(elem.Value.(*cachedClient), now):
+			metrics.ObserveClientCacheEviction("expired")
+		default:
+			return
+		}
+		c.remove(elem)
+	}
+}
+
+// expired はトークンが更新の時刻を過ぎているかを返す。次に使うときはトークンを取り直すため、保持し続ける意味がない。
+// まだトークンを取得していない（作成直後で取得中の）ものは期限切れとみなさない
+func (c *clientCache) expired(entry *cachedClient, now time.Time) bool {
+	_, refreshAt, err := entry.transport.Expiry()
+	return err == nil && !now.Before(refreshAt)
+}
+
+func (c *clientCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cachedClient).installationID)
+}
+
+// appClientCache は App 自身（JWT）として認証するクライアントを鍵が変わるまで使い回す
+type appClientCache struct {
+	newClient func(key []byte) (*github.Client, error)
+
+	mu     sync.Mutex
+	key    []byte
+	client *github.Client
+}
+
+func (c *appClientCache) get(key []byte) (*github.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.client != nil && bytes.Equal(c.key, key) {
+		return c.client, nil
+	}
+	client, err := c.newClient(key)
+	if err != nil {
+		return nil, err
+	}
+	c.key = key
+	c.client = client
+	return client, nil
+}
+
+// newInstallationClient はインストールトークンで認証するクライアントを作成する
+func (c *GitHubClient) newInstallationClient(installationID int64, key []byte) (*github.Client, *ghinstallation.Transport, error) {
+	itr, err := ghinstallation.New(
+		metricsTransport{base: tracing.Transport(http.DefaultTransport)},
+		c.appID,
+		installationID,
+		key,
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create installation transport: %w", err)
+	}
+
+	return github.NewClient(&http.Client{Transport: newRetryTransport(itr, c.retryPolicy)}), itr, nil
+}
+
+// newAppClient は App 自身（JWT）として認証するクライアントを作成する
+func (c *GitHubClient) newAppClient(key []byte) (*github.Client, error) {
+	atr, err := ghinstallation.NewAppsTransport(metricsTransport{base: tracing.Transport(http.DefaultTransport)}, c.appID, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create app transport: %w", err)
+	}
+
+	return github.NewClient(&http.Client{Transport: newRetryTransport(atr, c.retryPolicy)}), nil
+}
@@ -0,0 +1,55 @@
+package github
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/infrastructure/keyfile"
+)
+
+func TestGitHubClientSpansRecordErrors(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	// 秘密鍵が読めないため、どのメソッドも GitHub にアクセスする前に失敗する
+	client := NewGitHubClient(1, keyfile.Static("not a key"))
+	repo := entity.Repository{Owner: "org", Name: "app", InstallationID: 1}
+	ctx := context.Background()
+
+	tests := []struct {
+		span string
+		call func() error
+	}{
+		{"GitHubClient.GetFileContent", func() error { _, _, err := client.GetFileContent(ctx, repo, "README.md"); return err }},
+		{"GitHubClient.CreateFile", func() error { return client.CreateFile(ctx, repo, entity.File{Path: "README.md"}) }},
+		{"GitHubClient.ListLabels", func() error { _, err := client.ListLabels(ctx, repo); return err }},
+		{"GitHubClient.DeleteWorkflowFile", func() error { return client.DeleteWorkflowFile(ctx, repo, entity.SetupLabelsWorkflowPath) }},
+		{"GitHubClient.GetRepositoryMetadata", func() error { _, err := client.GetRepositoryMetadata(ctx, repo); return err }},
+		{"GitHubClient.FindRepositoryInstallation", func() error { _, err := client.FindRepositoryInstallation(ctx, "org", "app"); return err }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.span, func(t *testing.T) {
+			if err := tt.call(); err == nil {
+				t.Fatal("error = nil, want an invalid key error")
+			}
+			spans := recorder.Ended()
+			span := spans[len(spans)-1]
+			if span.Name() != tt.span {
+				t.Fatalf("last span = %s, want %s", span.Name(), tt.span)
+			}
+			if span.Status().Code != codes.Error || len(span.Events()) == 0 {
+				t.Errorf("span status = %v, events = %d, want the error recorded", span.Status(), len(span.Events()))
+			}
+		})
+	}
+}
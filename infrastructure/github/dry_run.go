@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"sync"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+)
+
+// DryRunClient は読み取りを base に任せ、変更を行わずに記録する GitHubRepository
+type DryRunClient struct {
+	base repository.GitHubRepository
+
+	mu      sync.Mutex
+	actions []entity.PlannedAction
+}
+
+func NewDryRunClient(base repository.GitHubRepository) *DryRunClient {
+	return &DryRunClient{base: base}
+}
+
+// Actions はこれまでに記録した変更を順番に返す
+func (c *DryRunClient) Actions() []entity.PlannedAction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]entity.PlannedAction(nil), c.actions...)
+}
+
+func (c *DryRunClient) record(action entity.PlannedAction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.actions = append(c.actions, action)
+	return nil
+}
+
+func (c *DryRunClient) GetRepositoryMetadata(ctx context.Context, repo entity.Repository) (entity.RepositoryMetadata, error) {
+	return c.base.GetRepositoryMetadata(ctx, repo)
+}
+
+func (c *DryRunClient) GetFileContent(ctx context.Context, repo entity.Repository, path string) (string, bool, error) {
+	return c.base.GetFileContent(ctx, repo, path)
+}
+
+func (c *DryRunClient) ListDirectoryFiles(ctx context.Context, repo entity.Repository, ref, dir string, match func(path string) bool) ([]entity.File, error) {
+	return c.base.ListDirectoryFiles(ctx, repo, ref, dir, match)
+}
+
+func (c *DryRunClient) CreateFile(ctx context.Context, repo entity.Repository, file entity.FileContent) error {
+	return c.record(entity.PlannedAction{
+		Type:       entity.PlannedCreateFile,
+		Repository: repo.FullName(),
+		Message:    file.GetMessage(),
+		Files:      []entity.PlannedFile{entity.NewPlannedFile(file)},
+	})
+}
+
+func (c *DryRunClient) CreateFiles(ctx context.Context, repo entity.Repository, files []entity.FileContent, commitMessage string) error {
+	planned := make([]entity.PlannedFile, 0, len(files))
+	for _, f := range files {
+		planned = append(planned, entity.NewPlannedFile(f))
+	}
+	return c.record(entity.PlannedAction{
+		Type:       entity.PlannedCreateFiles,
+		Repository: repo.FullName(),
+		Message:    commitMessage,
+		Files:      planned,
+	})
+}
+
+func (c *DryRunClient) DeleteWorkflowFile(ctx context.Context, repo entity.Repository, path string) error {
+	return c.record(entity.PlannedAction{Type: entity.PlannedDeleteFile, Repository: repo.FullName(), Path: path})
+}
+
+func (c *DryRunClient) CreateSecret(ctx context.Context, repo entity.Repository, secretName, secretValue string) error {
+	return c.record(entity.PlannedAction{Type: entity.PlannedCreateSecret, Repository: repo.FullName(), Secret: secretName})
+}
+
+func (c *DryRunClient) ListLabels(ctx context.Context, repo entity.Repository) ([]entity.Label, error) {
+	return c.base.ListLabels(ctx, repo)
+}
+
+func (c *DryRunClient) CreateLabel(ctx context.Context, repo entity.Repository, label entity.Label) error {
+	return c.record(entity.PlannedAction{Type: entity.PlannedCreateLabel, Repository: repo.FullName(), Label: &label})
+}
+
+func (c *DryRunClient) UpdateLabel(ctx context.Context, repo entity.Repository, name string, label entity.Label) error {
+	return c.record(entity.PlannedAction{Type: entity.PlannedUpdateLabel, Repository: repo.FullName(), Name: name, Label: &label})
+}
+
+func (c *DryRunClient) DeleteLabel(ctx context.Context, repo entity.Repository, name string) error {
+	return c.record(entity.PlannedAction{Type: entity.PlannedDeleteLabel, Repository: repo.FullName(), Name: name})
+}
+
+func (c *DryRunClient) FindRepositoryInstallation(ctx context.Context, owner, name string) (int64, error) {
+	return c.base.FindRepositoryInstallation(ctx, owner, name)
+}
+
+func (c *DryRunClient) ListInstallationRepositories(ctx context.Context, installationID int64) ([]entity.Repository, error) {
+	return c.base.ListInstallationRepositories(ctx, installationID)
+}
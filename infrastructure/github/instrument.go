@@ -0,0 +1,49 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github-setup-app/metrics"
+	"github-setup-app/tracing"
+)
+
+type methodKey struct{}
+
+// withMethod はメトリクスで API 呼び出しを区別するために GitHubClient のメソッド名を ctx に付ける
+func withMethod(ctx context.Context, method string) context.Context {
+	return context.WithValue(ctx, methodKey{}, method)
+}
+
+// startSpan は GitHubClient のメソッドのスパンを開始し、メトリクス用にメソッド名を ctx に付ける
+func startSpan(ctx context.Context, method string) (context.Context, trace.Span) {
+	return tracing.Start(withMethod(ctx, method), "GitHubClient."+method)
+}
+
+func methodFrom(ctx context.Context) string {
+	if m, ok := ctx.Value(methodKey{}).(string); ok {
+		return m
+	}
+	return "unknown"
+}
+
+// metricsTransport は HTTP リクエスト1回ごとの所要時間とステータスコードを記録する。
+// インストールトークンの取得も含めるため ghinstallation の下に置く
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+func (t metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	status := 0
+	if resp != nil {
+		status = resp.StatusCode
+	}
+	metrics.ObserveGitHubRequest(methodFrom(req.Context()), status, time.Since(start))
+	return resp, err
+}
@@ -0,0 +1,220 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+
+	"github-setup-app/metrics"
+)
+
+// RetryPolicy は GitHub API 呼び出しの再試行方針
+type RetryPolicy struct {
+	// MaxAttempts は最初の呼び出しを含めた最大試行回数（1 なら再試行しない）
+	MaxAttempts    int
+	InitialBackoff time.Duration
+	MaxBackoff     time.Duration
+	// RetryNotFound が true の場合、404 / 409 を一時的なエラーとして再試行する。
+	// 作成直後のリポジトリでは contents / secrets API がしばらく 404 や 409 を返すため。
+	RetryNotFound bool
+	// MaxRateLimitWait を超えて待つ必要があるレート制限は再試行せずにエラーを返す
+	MaxRateLimitWait time.Duration
+}
+
+func DefaultRetryPolicy() RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts:      5,
+		InitialBackoff:   time.Second,
+		MaxBackoff:       30 * time.Second,
+		RetryNotFound:    true,
+		MaxRateLimitWait: 2 * time.Minute,
+	}
+}
+
+// errorClass はレスポンスの分類
+type errorClass int
+
+const (
+	classSuccess errorClass = iota
+	// classTransient は時間をおけば成功する可能性があるエラー
+	classTransient
+	// classRateLimited はレート制限。待ち時間がわかる場合はそれに従う
+	classRateLimited
+	// classPermanent は再試行しても結果が変わらないエラー
+	classPermanent
+)
+
+func (c errorClass) String() string {
+	switch c {
+	case classSuccess:
+		return "success"
+	case classTransient:
+		return "transient"
+	case classRateLimited:
+		return "rate_limited"
+	default:
+		return "permanent"
+	}
+}
+
+type notFoundExpectedKey struct{}
+
+// expectNotFound は 404 / 409 が正常な結果として返りうる呼び出し（存在確認など）で
+// RetryNotFound による再試行を無効にする
+func expectNotFound(ctx context.Context) context.Context {
+	return context.WithValue(ctx, notFoundExpectedKey{}, true)
+}
+
+// retryTransport は一時的なエラーとレート制限をジッター付き指数バックオフで再試行する
+type retryTransport struct {
+	base   http.RoundTripper
+	policy RetryPolicy
+	now    func() time.Time
+}
+
+func newRetryTransport(base http.RoundTripper, policy RetryPolicy) *retryTransport {
+	return &retryTransport{base: base, policy: policy, now: time.Now}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			// 再送するためにボディを作り直す
+			if req.Body != nil && req.Body != http.NoBody {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(ctx)
+				req.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		class, wait := t.classify(req, resp, err)
+		if class == classSuccess || class == classPermanent || attempt >= t.policy.MaxAttempts {
+			return resp, err
+		}
+		if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+			return resp, err
+		}
+		if class == classRateLimited && wait > t.policy.MaxRateLimitWait {
+			return resp, err
+		}
+		if wait <= 0 {
+			wait = t.backoff(attempt)
+		}
+
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		metrics.ObserveGitHubRetry(methodFrom(ctx), class.String())
+		slog.WarnContext(ctx, "GitHub API request failed, retrying",
+			"method", req.Method,
+			"path", req.URL.Path,
+			"class", class.String(),
+			"status", status,
+			"error", err,
+			"wait", wait.Round(time.Millisecond),
+			"attempt", attempt,
+			"max_attempts", t.policy.MaxAttempts,
+		)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// classify はレスポンスを分類し、サーバーから指示された待ち時間があれば返す
+func (t *retryTransport) classify(req *http.Request, resp *http.Response, err error) (errorClass, time.Duration) {
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return classPermanent, 0
+		}
+		// インストールトークンの取得に失敗した場合はそのレスポンスで分類する。
+		// 401 は鍵の誤り、403 / 404 はインストールの停止・削除のため、404 も再試行しない
+		var httpErr *ghinstallation.HTTPError
+		if errors.As(err, &httpErr) && httpErr.Response != nil {
+			return t.classifyStatus(httpErr.Response, false)
+		}
+		// ネットワークエラー
+		return classTransient, 0
+	}
+
+	retryNotFound := t.policy.RetryNotFound && req.Context().Value(notFoundExpectedKey{}) == nil
+	return t.classifyStatus(resp, retryNotFound)
+}
+
+// classifyStatus はステータスコードでレスポンスを分類する。retryNotFound が true なら 404 / 409 を一時的なエラーとする
+func (t *retryTransport) classifyStatus(resp *http.Response, retryNotFound bool) (errorClass, time.Duration) {
+	switch code := resp.StatusCode; {
+	case code < 400:
+		return classSuccess, 0
+	case code == http.StatusTooManyRequests:
+		return classRateLimited, t.rateLimitWait(resp)
+	case code == http.StatusForbidden:
+		// セカンダリレート制限は Retry-After、プライマリは X-RateLimit-Remaining: 0 で判別する
+		if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			return classRateLimited, t.rateLimitWait(resp)
+		}
+		return classPermanent, 0
+	case code == http.StatusNotFound || code == http.StatusConflict:
+		if retryNotFound {
+			return classTransient, 0
+		}
+		return classPermanent, 0
+	case code >= 500:
+		return classTransient, 0
+	default:
+		return classPermanent, 0
+	}
+}
+
+// rateLimitWait は Retry-After または X-RateLimit-Reset から待ち時間を求める
+func (t *retryTransport) rateLimitWait(resp *http.Response) time.Duration {
+	if v := resp.Header.Get("Retry-After"); v != "" {
+		if secs, err := strconv.Atoi(v); err == nil {
+			return time.Duration(secs) * time.Second
+		}
+	}
+	if v := resp.Header.Get("X-RateLimit-Reset"); v != "" {
+		if reset, err := strconv.ParseInt(v, 10, 64); err == nil {
+			// 時計のずれを考慮して1秒余分に待つ
+			if wait := time.Unix(reset, 0).Sub(t.now()) + time.Second; wait > 0 {
+				return wait
+			}
+		}
+	}
+	return 0
+}
+
+// backoff は attempt 回目の失敗後の待ち時間を返す（[d/2, d) の範囲でジッターを加える）
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.policy.InitialBackoff
+	for i := 1; i < attempt && d < t.policy.MaxBackoff; i++ {
+		d *= 2
+	}
+	d = min(d, t.policy.MaxBackoff)
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(d-half)
+}
@@ -0,0 +1,239 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation/v2"
+)
+
+// tokenError は ghinstallation がインストールトークンの取得に失敗したときのエラーを作る
+func tokenError(resp *http.Response) error {
+	if resp != nil && resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	var cause error
+	if resp == nil {
+		cause = errors.New("connection reset")
+	}
+	return fmt.Errorf("could not refresh installation id 1's token: %w", &ghinstallation.HTTPError{
+		Message:        "could not get access_tokens",
+		RootCause:      cause,
+		InstallationID: 1,
+		Response:       resp,
+	})
+}
+
+func TestRetryTransportClassify(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	reset := strconv.FormatInt(now.Add(10*time.Second).Unix(), 10)
+
+	tests := []struct {
+		name          string
+		status        int
+		header        http.Header
+		err           error
+		expectMissing bool
+		retryNotFound bool
+		wantClass     errorClass
+		wantWait      time.Duration
+	}{
+		{name: "ok", status: http.StatusOK, wantClass: classSuccess},
+		{name: "not modified", status: http.StatusNotModified, wantClass: classSuccess},
+		{name: "network error", err: errors.New("connection reset"), wantClass: classTransient},
+		{name: "token request network error", err: tokenError(nil), wantClass: classTransient},
+		{name: "token with invalid key", err: tokenError(&http.Response{StatusCode: http.StatusUnauthorized}), wantClass: classPermanent},
+		{name: "token for suspended installation", err: tokenError(&http.Response{StatusCode: http.StatusForbidden}), wantClass: classPermanent},
+		{name: "token for removed installation", err: tokenError(&http.Response{StatusCode: http.StatusNotFound}), retryNotFound: true, wantClass: classPermanent},
+		{name: "token server error", err: tokenError(&http.Response{StatusCode: http.StatusBadGateway}), wantClass: classTransient},
+		{
+			name:      "token rate limited",
+			err:       tokenError(&http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{"Retry-After": {"5"}}}),
+			wantClass: classRateLimited,
+			wantWait:  5 * time.Second,
+		},
+		{name: "canceled", err: context.Canceled, wantClass: classPermanent},
+		{name: "deadline exceeded", err: context.DeadlineExceeded, wantClass: classPermanent},
+		{name: "server error", status: http.StatusBadGateway, wantClass: classTransient},
+		{name: "bad request", status: http.StatusUnprocessableEntity, wantClass: classPermanent},
+		{name: "unauthorized", status: http.StatusUnauthorized, wantClass: classPermanent},
+		{name: "forbidden", status: http.StatusForbidden, header: http.Header{"X-Ratelimit-Remaining": {"12"}}, wantClass: classPermanent},
+		{
+			name:      "too many requests",
+			status:    http.StatusTooManyRequests,
+			header:    http.Header{"Retry-After": {"3"}},
+			wantClass: classRateLimited,
+			wantWait:  3 * time.Second,
+		},
+		{
+			name:      "secondary rate limit",
+			status:    http.StatusForbidden,
+			header:    http.Header{"Retry-After": {"60"}},
+			wantClass: classRateLimited,
+			wantWait:  time.Minute,
+		},
+		{
+			name:      "primary rate limit",
+			status:    http.StatusForbidden,
+			header:    http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {reset}},
+			wantClass: classRateLimited,
+			wantWait:  11 * time.Second,
+		},
+		{
+			name:      "rate limit reset in the past",
+			status:    http.StatusForbidden,
+			header:    http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {"1"}},
+			wantClass: classRateLimited,
+		},
+		{
+			name:      "invalid Retry-After",
+			status:    http.StatusTooManyRequests,
+			header:    http.Header{"Retry-After": {"Wed, 21 Oct 2015 07:28:00 GMT"}},
+			wantClass: classRateLimited,
+		},
+		{name: "not found retried", status: http.StatusNotFound, retryNotFound: true, wantClass: classTransient},
+		{name: "conflict retried", status: http.StatusConflict, retryNotFound: true, wantClass: classTransient},
+		{name: "not found without RetryNotFound", status: http.StatusNotFound, wantClass: classPermanent},
+		{name: "not found expected", status: http.StatusNotFound, retryNotFound: true, expectMissing: true, wantClass: classPermanent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy := DefaultRetryPolicy()
+			policy.RetryNotFound = tt.retryNotFound
+			transport := newRetryTransport(http.DefaultTransport, policy)
+			transport.now = func() time.Time { return now }
+
+			ctx := context.Background()
+			if tt.expectMissing {
+				ctx = expectNotFound(ctx)
+			}
+			req, _ := http.NewRequestWithContext(ctx, http.MethodGet, "https://api.github.com/repos/org/app", nil)
+			var resp *http.Response
+			if tt.err == nil {
+				header := tt.header
+				if header == nil {
+					header = http.Header{}
+				}
+				resp = &http.Response{StatusCode: tt.status, Header: header}
+			}
+
+			class, wait := transport.classify(req, resp, tt.err)
+			if class != tt.wantClass || wait != tt.wantWait {
+				t.Errorf("classify() = %s, %v, want %s, %v", class, wait, tt.wantClass, tt.wantWait)
+			}
+		})
+	}
+}
+
+func TestRetryTransportBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	transport := newRetryTransport(http.DefaultTransport, policy)
+
+	tests := []struct {
+		attempt int
+		// ジッターにより [max/2, max) の範囲になる
+		max time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{20, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for range 50 {
+			got := transport.backoff(tt.attempt)
+			if got < tt.max/2 || got >= tt.max {
+				t.Fatalf("backoff(%d) = %v, want in [%v, %v)", tt.attempt, got, tt.max/2, tt.max)
+			}
+		}
+	}
+
+	// 待ち時間が 0 の設定ではすぐに再試行する
+	transport.policy = RetryPolicy{}
+	if got := transport.backoff(3); got != 0 {
+		t.Errorf("backoff() with zero policy = %v, want 0", got)
+	}
+}
+
+// roundTripFunc は関数を http.RoundTripper として使う
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestRetryTransportRoundTrip(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		maxAttempts  int
+		wantStatus   int
+		wantAttempts int
+	}{
+		{name: "success", statuses: []int{200}, maxAttempts: 3, wantStatus: 200, wantAttempts: 1},
+		{name: "recovers from server error", statuses: []int{502, 503, 201}, maxAttempts: 3, wantStatus: 201, wantAttempts: 3},
+		{name: "gives up after max attempts", statuses: []int{500, 500, 500, 200}, maxAttempts: 3, wantStatus: 500, wantAttempts: 3},
+		{name: "permanent error is not retried", statuses: []int{422, 200}, maxAttempts: 3, wantStatus: 422, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var bodies []string
+			base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+				b, _ := io.ReadAll(req.Body)
+				bodies = append(bodies, string(b))
+				return &http.Response{
+					StatusCode: tt.statuses[len(bodies)-1],
+					Header:     http.Header{},
+					Body:       io.NopCloser(strings.NewReader("")),
+				}, nil
+			})
+			transport := newRetryTransport(base, RetryPolicy{MaxAttempts: tt.maxAttempts, MaxRateLimitWait: time.Minute})
+
+			req, _ := http.NewRequest(http.MethodPut, "https://api.github.com/repos/org/app/contents/README.md", strings.NewReader(`{"content":"x"}`))
+			resp, err := transport.RoundTrip(req)
+			if err != nil {
+				t.Fatalf("RoundTrip() error = %v", err)
+			}
+			if resp.StatusCode != tt.wantStatus || len(bodies) != tt.wantAttempts {
+				t.Errorf("RoundTrip() = %d after %d attempts, want %d after %d", resp.StatusCode, len(bodies), tt.wantStatus, tt.wantAttempts)
+			}
+			// 再試行でもボディを送り直す
+			for i, b := range bodies {
+				if b != `{"content":"x"}` {
+					t.Errorf("attempt %d body = %q", i+1, b)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryTransportRateLimitTooLong(t *testing.T) {
+	attempts := 0
+	base := roundTripFunc(func(*http.Request) (*http.Response, error) {
+		attempts++
+		return &http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Header:     http.Header{"Retry-After": {"3600"}},
+			Body:       http.NoBody,
+		}, nil
+	})
+	transport := newRetryTransport(base, RetryPolicy{MaxAttempts: 5, MaxRateLimitWait: time.Minute})
+
+	req, _ := http.NewRequest(http.MethodGet, "https://api.github.com/rate_limit", nil)
+	resp, err := transport.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusTooManyRequests || attempts != 1 {
+		t.Errorf("RoundTrip() = %v, %v after %d attempts, want 429 without retry", resp, err, attempts)
+	}
+}
@@ -0,0 +1,97 @@
+package keyfile
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// Static は入れ替わらない秘密鍵（環境変数で渡した鍵）
+type Static []byte
+
+func (k Static) PrivateKey() []byte { return k }
+
+// ParseFunc はファイルの内容を検証し、PEM の秘密鍵に揃える
+type ParseFunc func(content string) ([]byte, error)
+
+// Watcher はファイルの秘密鍵を定期的に読み直し、内容が変わったら差し替える。
+// Kubernetes の Secret のようにシンボリックリンクごと入れ替わる場合にも追従するため、
+// ファイルシステムの通知ではなく内容の比較で変更を検知する
+type Watcher struct {
+	path  string
+	parse ParseFunc
+
+	mu  sync.RWMutex
+	raw []byte
+	key []byte
+}
+
+// NewWatcher は path の秘密鍵を読み込む。読み込めない場合や鍵として正しくない場合はエラー
+func NewWatcher(path string, parse ParseFunc) (*Watcher, error) {
+	w := &Watcher{path: path, parse: parse}
+	if _, err := w.reload(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// PrivateKey は最後に読み込めた秘密鍵を返す
+func (w *Watcher) PrivateKey() []byte {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	return w.key
+}
+
+// Run は ctx がキャンセルされるまで interval ごとにファイルを確認する。
+// 新しい内容が鍵として正しくない場合は、以前の鍵を使い続ける
+func (w *Watcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := w.reload()
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to reload private key, keeping the previous key", "path", w.path, "error", err)
+			continue
+		}
+		if changed {
+			slog.InfoContext(ctx, "reloaded private key", "path", w.path)
+		}
+	}
+}
+
+// reload はファイルを読み直し、内容が変わっていれば鍵を差し替える
+func (w *Watcher) reload() (bool, error) {
+	raw, err := os.ReadFile(w.path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read private key file: %w", err)
+	}
+
+	w.mu.RLock()
+	same := w.key != nil && bytes.Equal(raw, w.raw)
+	w.mu.RUnlock()
+	if same {
+		return false, nil
+	}
+
+	key, err := w.parse(string(raw))
+	if err != nil {
+		return false, fmt.Errorf("invalid private key in %s: %w", w.path, err)
+	}
+
+	w.mu.Lock()
+	w.raw = raw
+	w.key = key
+	w.mu.Unlock()
+	return true, nil
+}
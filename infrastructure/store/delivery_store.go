@@ -0,0 +1,151 @@
+package store
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// deliveryLog は TTL と最大件数で上限を設けた配信 ID の記録
+type deliveryLog struct {
+	ttl     time.Duration
+	maxSize int
+	order   *list.List // 古い順の *deliveryEntry
+	entries map[string]*list.Element
+}
+
+type deliveryEntry struct {
+	ID     string    `json:"id"`
+	SeenAt time.Time `json:"seen_at"`
+}
+
+func newDeliveryLog(ttl time.Duration, maxSize int) *deliveryLog {
+	return &deliveryLog{
+		ttl:     ttl,
+		maxSize: maxSize,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (l *deliveryLog) markSeen(id string, now time.Time) bool {
+	l.expire(now)
+	if _, ok := l.entries[id]; ok {
+		return false
+	}
+
+	l.entries[id] = l.order.PushBack(&deliveryEntry{ID: id, SeenAt: now})
+	for l.maxSize > 0 && l.order.Len() > l.maxSize {
+		l.remove(l.order.Front())
+	}
+	return true
+}
+
+func (l *deliveryLog) forget(id string) {
+	if e, ok := l.entries[id]; ok {
+		l.remove(e)
+	}
+}
+
+func (l *deliveryLog) expire(now time.Time) {
+	for e := l.order.Front(); e != nil; e = l.order.Front() {
+		if now.Sub(e.Value.(*deliveryEntry).SeenAt) < l.ttl {
+			return
+		}
+		l.remove(e)
+	}
+}
+
+func (l *deliveryLog) remove(e *list.Element) {
+	delete(l.entries, e.Value.(*deliveryEntry).ID)
+	l.order.Remove(e)
+}
+
+func (l *deliveryLog) snapshot() []deliveryEntry {
+	entries := make([]deliveryEntry, 0, l.order.Len())
+	for e := l.order.Front(); e != nil; e = e.Next() {
+		entries = append(entries, *e.Value.(*deliveryEntry))
+	}
+	return entries
+}
+
+// MemoryDeliveryStore はメモリ上に配信 ID を記録する
+type MemoryDeliveryStore struct {
+	mu  sync.Mutex
+	log *deliveryLog
+}
+
+func NewMemoryDeliveryStore(ttl time.Duration, maxSize int) *MemoryDeliveryStore {
+	return &MemoryDeliveryStore{log: newDeliveryLog(ttl, maxSize)}
+}
+
+func (s *MemoryDeliveryStore) MarkSeen(ctx context.Context, id string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.log.markSeen(id, now), nil
+}
+
+func (s *MemoryDeliveryStore) Forget(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.log.forget(id)
+	return nil
+}
+
+// FileDeliveryStore は配信 ID を JSON ファイルに保存し、再起動後も重複を検出する
+type FileDeliveryStore struct {
+	mu   sync.Mutex
+	path string
+	log  *deliveryLog
+}
+
+func NewFileDeliveryStore(path string, ttl time.Duration, maxSize int) (*FileDeliveryStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create delivery store directory: %w", err)
+	}
+
+	s := &FileDeliveryStore{path: path, log: newDeliveryLog(ttl, maxSize)}
+
+	var entries []deliveryEntry
+	if err := readJSON(path, &entries); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].SeenAt.Before(entries[j].SeenAt)
+	})
+	for _, e := range entries {
+		s.log.markSeen(e.ID, e.SeenAt)
+	}
+
+	return s, nil
+}
+
+func (s *FileDeliveryStore) MarkSeen(ctx context.Context, id string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.log.markSeen(id, now) {
+		return false, nil
+	}
+	if err := writeJSON(s.path, s.log.snapshot()); err != nil {
+		s.log.forget(id)
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *FileDeliveryStore) Forget(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.log.forget(id)
+	return writeJSON(s.path, s.log.snapshot())
+}
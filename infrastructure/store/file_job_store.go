@@ -0,0 +1,229 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github-setup-app/domain/entity"
+)
+
+// FileJobStore はジョブを dir 以下に1件1ファイルの JSON として保存する。
+// 1つのディレクトリを使うプロセスは1つだけであることを前提とし、起動時に読み込んだ内容をメモリに保持する
+// （ポーリングのたびに全てのファイルを読み直さない）。変更はファイルに書き込んでからメモリに反映する。
+type FileJobStore struct {
+	mu   sync.Mutex
+	dir  string
+	jobs map[string]entity.Job
+}
+
+// NewFileJobStore は dir のジョブを読み込む。読めないファイルや壊れたファイルは
+// .corrupt を付けた名前に移して読み飛ばし、他のジョブの実行を止めない
+func NewFileJobStore(dir string) (*FileJobStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create job store directory: %w", err)
+	}
+	s := &FileJobStore{dir: dir, jobs: make(map[string]entity.Job)}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *FileJobStore) Enqueue(ctx context.Context, job *entity.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; ok {
+		return fmt.Errorf("job %s already exists", job.ID)
+	}
+	return s.write(job)
+}
+
+func (s *FileJobStore) Claim(ctx context.Context, now time.Time) (*entity.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := nextRunnable(jobList(s.jobs), now)
+	if job == nil {
+		return nil, nil
+	}
+	job.Status = entity.JobStatusRunning
+	job.Attempts++
+	job.UpdatedAt = now
+	if err := s.write(job); err != nil {
+		return nil, err
+	}
+	return job, nil
+}
+
+func (s *FileJobStore) Save(ctx context.Context, job *entity.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return nil
+	}
+	return s.write(job)
+}
+
+func (s *FileJobStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.remove(id)
+}
+
+func (s *FileJobStore) DeleteByInstallation(ctx context.Context, installationID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, job := range s.jobs {
+		if job.Repository.InstallationID != installationID {
+			continue
+		}
+		if err := s.remove(id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *FileJobStore) DeleteFailed(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, job := range s.jobs {
+		if job.Status != entity.JobStatusFailed || !job.UpdatedAt.Before(before) {
+			continue
+		}
+		if err := s.remove(id); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *FileJobStore) ResetRunning(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for _, job := range s.jobs {
+		if job.Status != entity.JobStatusRunning {
+			continue
+		}
+		job.Status = entity.JobStatusPending
+		if err := s.write(&job); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *FileJobStore) CountByStatus(ctx context.Context) (map[entity.JobStatus]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// 件数はメモリから返すが、readiness の確認のためディレクトリが使えることも確かめる
+	if _, err := os.Stat(s.dir); err != nil {
+		return nil, fmt.Errorf("failed to access job store directory: %w", err)
+	}
+	return countByStatus(jobList(s.jobs)), nil
+}
+
+func (s *FileJobStore) path(id string) string {
+	return filepath.Join(s.dir, id+".json")
+}
+
+// load はディレクトリのジョブを読み込む。読めないファイルはログに記録して隔離する
+func (s *FileJobStore) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return fmt.Errorf("failed to read job store directory: %w", err)
+	}
+
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".json") {
+			continue
+		}
+		path := filepath.Join(s.dir, e.Name())
+		var job entity.Job
+		if err := readJSON(path, &job); err != nil {
+			s.quarantine(path, err)
+			continue
+		}
+		if job.ID+".json" != e.Name() {
+			s.quarantine(path, fmt.Errorf("job id %q does not match the file name", job.ID))
+			continue
+		}
+		s.jobs[job.ID] = job
+	}
+	return nil
+}
+
+// quarantine は読めないジョブのファイルを .corrupt を付けた名前に移し、以降の読み込みから外す
+func (s *FileJobStore) quarantine(path string, cause error) {
+	dest := path + ".corrupt"
+	if err := os.Rename(path, dest); err != nil {
+		slog.Error("skipping unreadable job file", "path", path, "error", cause, "quarantine_error", err)
+		return
+	}
+	slog.Error("quarantined unreadable job file", "path", path, "moved_to", dest, "error", cause)
+}
+
+func (s *FileJobStore) remove(id string) error {
+	if err := os.Remove(s.path(id)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to delete job %s: %w", id, err)
+	}
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *FileJobStore) write(job *entity.Job) error {
+	if err := writeJSON(s.path(job.ID), job); err != nil {
+		return err
+	}
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func readJSON(path string, v any) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return nil
+}
+
+// writeJSON は一時ファイルに書き込んでから rename し、途中で終了しても壊れたファイルを残さない
+func writeJSON(path string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", path, err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to rename %s: %w", tmp, err)
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+)
+
+var jobStoreBase = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func testJob(id string, created time.Duration, status entity.JobStatus) *entity.Job {
+	return &entity.Job{
+		ID:         id,
+		Type:       entity.JobTypeSetupRepository,
+		Repository: entity.Repository{Owner: "org", Name: id, InstallationID: 1},
+		Status:     status,
+		CreatedAt:  jobStoreBase.Add(created),
+		UpdatedAt:  jobStoreBase.Add(created),
+		RunAfter:   jobStoreBase.Add(created),
+	}
+}
+
+// jobStores は同じ振る舞いを確かめるストアを作成する
+func jobStores(t *testing.T) map[string]func() repository.JobStore {
+	return map[string]func() repository.JobStore{
+		"memory": func() repository.JobStore { return NewMemoryJobStore() },
+		"file": func() repository.JobStore {
+			s, err := NewFileJobStore(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			return s
+		},
+	}
+}
+
+func TestJobStoreClaim(t *testing.T) {
+	delayed := testJob("delayed", 0, entity.JobStatusPending)
+	delayed.RunAfter = jobStoreBase.Add(time.Hour)
+
+	tests := []struct {
+		name string
+		jobs []*entity.Job
+		// want は Claim を繰り返したときに返るジョブの ID（最後は nil）
+		want []string
+	}{
+		{name: "empty", want: nil},
+		{
+			name: "oldest first",
+			jobs: []*entity.Job{testJob("b", 2*time.Second, entity.JobStatusPending), testJob("a", time.Second, entity.JobStatusPending)},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "skips running and failed",
+			jobs: []*entity.Job{testJob("running", 0, entity.JobStatusRunning), testJob("failed", 0, entity.JobStatusFailed), testJob("pending", time.Second, entity.JobStatusPending)},
+			want: []string{"pending"},
+		},
+		{
+			name: "waits for run_after",
+			jobs: []*entity.Job{delayed, testJob("ready", time.Second, entity.JobStatusPending)},
+			want: []string{"ready"},
+		},
+	}
+
+	for storeName, newStore := range jobStores(t) {
+		for _, tt := range tests {
+			t.Run(storeName+"/"+tt.name, func(t *testing.T) {
+				ctx := context.Background()
+				s := newStore()
+				for _, job := range tt.jobs {
+					job := *job
+					if err := s.Enqueue(ctx, &job); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				now := jobStoreBase.Add(time.Minute)
+				var got []string
+				for {
+					job, err := s.Claim(ctx, now)
+					if err != nil {
+						t.Fatalf("Claim() error = %v", err)
+					}
+					if job == nil {
+						break
+					}
+					if job.Status != entity.JobStatusRunning || job.Attempts != 1 || !job.UpdatedAt.Equal(now) {
+						t.Errorf("Claim() = status %s, attempts %d, updated %v", job.Status, job.Attempts, job.UpdatedAt)
+					}
+					got = append(got, job.ID)
+				}
+				if len(got) != len(tt.want) {
+					t.Fatalf("claimed %v, want %v", got, tt.want)
+				}
+				for i := range got {
+					if got[i] != tt.want[i] {
+						t.Errorf("claimed %v, want %v", got, tt.want)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestJobStoreResetRunning(t *testing.T) {
+	for storeName, newStore := range jobStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore()
+			s.Enqueue(ctx, testJob("a", 0, entity.JobStatusPending))
+			s.Enqueue(ctx, testJob("b", time.Second, entity.JobStatusFailed))
+
+			if _, err := s.Claim(ctx, jobStoreBase.Add(time.Minute)); err != nil {
+				t.Fatal(err)
+			}
+			n, err := s.ResetRunning(ctx)
+			if err != nil || n != 1 {
+				t.Fatalf("ResetRunning() = %d, %v, want 1", n, err)
+			}
+
+			// 戻したジョブは試行回数を保ったまま再び取得できる
+			job, _ := s.Claim(ctx, jobStoreBase.Add(time.Minute))
+			if job == nil || job.ID != "a" || job.Attempts != 2 {
+				t.Errorf("Claim() after reset = %+v, want a with 2 attempts", job)
+			}
+		})
+	}
+}
+
+func TestJobStoreDelete(t *testing.T) {
+	for storeName, newStore := range jobStores(t) {
+		t.Run(storeName, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore()
+			old := testJob("old", 0, entity.JobStatusFailed)
+			recent := testJob("recent", 2*time.Hour, entity.JobStatusFailed)
+			pending := testJob("pending", 0, entity.JobStatusPending)
+			other := testJob("other", 0, entity.JobStatusPending)
+			other.Repository.InstallationID = 2
+			for _, job := range []*entity.Job{old, recent, pending, other} {
+				s.Enqueue(ctx, job)
+			}
+
+			if n, err := s.DeleteFailed(ctx, jobStoreBase.Add(time.Hour)); err != nil || n != 1 {
+				t.Errorf("DeleteFailed() = %d, %v, want 1", n, err)
+			}
+			if n, err := s.DeleteByInstallation(ctx, 2); err != nil || n != 1 {
+				t.Errorf("DeleteByInstallation() = %d, %v, want 1", n, err)
+			}
+			if err := s.Delete(ctx, "pending"); err != nil {
+				t.Errorf("Delete() error = %v", err)
+			}
+
+			// 削除済みのジョブは Save しても戻らない
+			if err := s.Save(ctx, pending); err != nil {
+				t.Errorf("Save() error = %v", err)
+			}
+			counts, _ := s.CountByStatus(ctx)
+			if len(counts) != 1 || counts[entity.JobStatusFailed] != 1 {
+				t.Errorf("CountByStatus() = %v, want only the recent failed job", counts)
+			}
+		})
+	}
+}
+
+func TestFileJobStorePersists(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Enqueue(ctx, testJob("a", 0, entity.JobStatusPending))
+	s.Enqueue(ctx, testJob("b", time.Second, entity.JobStatusPending))
+	s.Claim(ctx, jobStoreBase.Add(time.Minute))
+
+	// 実行中に終了した場合も試行回数は保存されている
+	reopened, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := reopened.ResetRunning(ctx); n != 1 {
+		t.Fatalf("ResetRunning() = %d, want 1", n)
+	}
+	job, _ := reopened.Claim(ctx, jobStoreBase.Add(time.Minute))
+	if job == nil || job.ID != "a" || job.Attempts != 2 {
+		t.Errorf("Claim() after restart = %+v, want a with 2 attempts", job)
+	}
+}
+
+func TestFileJobStoreQuarantine(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	s, _ := NewFileJobStore(dir)
+	s.Enqueue(ctx, testJob("good", 0, entity.JobStatusPending))
+	os.WriteFile(filepath.Join(dir, "broken.json"), []byte("{"), 0o600)
+	data, _ := os.ReadFile(filepath.Join(dir, "good.json"))
+	os.WriteFile(filepath.Join(dir, "renamed.json"), data, 0o600)
+
+	reopened, err := NewFileJobStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileJobStore() error = %v", err)
+	}
+	counts, _ := reopened.CountByStatus(ctx)
+	if counts[entity.JobStatusPending] != 1 {
+		t.Errorf("CountByStatus() = %v, want the good job only", counts)
+	}
+	for _, name := range []string{"broken.json.corrupt", "renamed.json.corrupt"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("%s was not quarantined: %v", name, err)
+		}
+	}
+}
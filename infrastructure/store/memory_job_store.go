@@ -0,0 +1,145 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github-setup-app/domain/entity"
+)
+
+// MemoryJobStore はメモリ上にジョブを保持する。プロセスを再起動するとジョブは失われる
+type MemoryJobStore struct {
+	mu   sync.Mutex
+	jobs map[string]entity.Job
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: make(map[string]entity.Job)}
+}
+
+func (s *MemoryJobStore) Enqueue(ctx context.Context, job *entity.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; ok {
+		return fmt.Errorf("job %s already exists", job.ID)
+	}
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *MemoryJobStore) Claim(ctx context.Context, now time.Time) (*entity.Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job := nextRunnable(jobList(s.jobs), now)
+	if job == nil {
+		return nil, nil
+	}
+	job.Status = entity.JobStatusRunning
+	job.Attempts++
+	job.UpdatedAt = now
+	s.jobs[job.ID] = *job
+	return job, nil
+}
+
+func (s *MemoryJobStore) Save(ctx context.Context, job *entity.Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; ok {
+		s.jobs[job.ID] = *job
+	}
+	return nil
+}
+
+func (s *MemoryJobStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *MemoryJobStore) DeleteByInstallation(ctx context.Context, installationID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, job := range s.jobs {
+		if job.Repository.InstallationID == installationID {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryJobStore) DeleteFailed(ctx context.Context, before time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, job := range s.jobs {
+		if job.Status == entity.JobStatusFailed && job.UpdatedAt.Before(before) {
+			delete(s.jobs, id)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryJobStore) ResetRunning(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, job := range s.jobs {
+		if job.Status == entity.JobStatusRunning {
+			job.Status = entity.JobStatusPending
+			s.jobs[id] = job
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (s *MemoryJobStore) CountByStatus(ctx context.Context) (map[entity.JobStatus]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return countByStatus(jobList(s.jobs)), nil
+}
+
+// jobList は map のジョブをスライスにコピーする
+func jobList(jobs map[string]entity.Job) []entity.Job {
+	list := make([]entity.Job, 0, len(jobs))
+	for _, job := range jobs {
+		list = append(list, job)
+	}
+	return list
+}
+
+// nextRunnable は実行可能なジョブのうち最も古いものを返す
+func nextRunnable(jobs []entity.Job, now time.Time) *entity.Job {
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	for i := range jobs {
+		if jobs[i].Status == entity.JobStatusPending && !jobs[i].RunAfter.After(now) {
+			return &jobs[i]
+		}
+	}
+	return nil
+}
+
+func countByStatus(jobs []entity.Job) map[entity.JobStatus]int {
+	counts := make(map[entity.JobStatus]int)
+	for _, job := range jobs {
+		counts[job.Status]++
+	}
+	return counts
+}
@@ -0,0 +1,169 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github-setup-app/domain/entity"
+)
+
+// runKey はリポジトリ名の大文字小文字を区別しないキーを返す
+func runKey(owner, name string) string {
+	return strings.ToLower(owner) + "/" + strings.ToLower(name)
+}
+
+func sortRuns(runs []entity.SetupRun) {
+	sort.Slice(runs, func(i, j int) bool {
+		return runs[i].StartedAt.After(runs[j].StartedAt)
+	})
+}
+
+// MemorySetupRunStore はメモリ上に実行記録を保持する
+type MemorySetupRunStore struct {
+	mu   sync.Mutex
+	runs map[string]entity.SetupRun
+}
+
+func NewMemorySetupRunStore() *MemorySetupRunStore {
+	return &MemorySetupRunStore{runs: make(map[string]entity.SetupRun)}
+}
+
+func (s *MemorySetupRunStore) Save(ctx context.Context, run *entity.SetupRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.runs[runKey(run.Repository.Owner, run.Repository.Name)] = *run
+	return nil
+}
+
+func (s *MemorySetupRunStore) Get(ctx context.Context, owner, name string) (*entity.SetupRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	run, ok := s.runs[runKey(owner, name)]
+	if !ok {
+		return nil, nil
+	}
+	return &run, nil
+}
+
+func (s *MemorySetupRunStore) List(ctx context.Context) ([]entity.SetupRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	runs := make([]entity.SetupRun, 0, len(s.runs))
+	for _, run := range s.runs {
+		runs = append(runs, run)
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+func (s *MemorySetupRunStore) DeleteByInstallation(ctx context.Context, installationID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, run := range s.runs {
+		if run.Repository.InstallationID == installationID {
+			delete(s.runs, key)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// FileSetupRunStore は実行記録を dir/<owner>/<repo>.json に保存する
+type FileSetupRunStore struct {
+	mu  sync.Mutex
+	dir string
+}
+
+func NewFileSetupRunStore(dir string) (*FileSetupRunStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create setup run store directory: %w", err)
+	}
+	return &FileSetupRunStore{dir: dir}, nil
+}
+
+func (s *FileSetupRunStore) Save(ctx context.Context, run *entity.SetupRun) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	path := s.path(run.Repository.Owner, run.Repository.Name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create setup run directory: %w", err)
+	}
+	return writeJSON(path, run)
+}
+
+func (s *FileSetupRunStore) Get(ctx context.Context, owner, name string) (*entity.SetupRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var run entity.SetupRun
+	if err := readJSON(s.path(owner, name), &run); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (s *FileSetupRunStore) List(ctx context.Context) ([]entity.SetupRun, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*", "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list setup runs: %w", err)
+	}
+
+	runs := make([]entity.SetupRun, 0, len(paths))
+	for _, path := range paths {
+		var run entity.SetupRun
+		if err := readJSON(path, &run); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	sortRuns(runs)
+	return runs, nil
+}
+
+func (s *FileSetupRunStore) DeleteByInstallation(ctx context.Context, installationID int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	paths, err := filepath.Glob(filepath.Join(s.dir, "*", "*.json"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to list setup runs: %w", err)
+	}
+
+	n := 0
+	for _, path := range paths {
+		var run entity.SetupRun
+		if err := readJSON(path, &run); err != nil {
+			return n, err
+		}
+		if run.Repository.InstallationID != installationID {
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return n, fmt.Errorf("failed to delete setup run %s: %w", path, err)
+		}
+		n++
+	}
+	return n, nil
+}
+
+func (s *FileSetupRunStore) path(owner, name string) string {
+	return filepath.Join(s.dir, strings.ToLower(owner), strings.ToLower(name)+".json")
+}
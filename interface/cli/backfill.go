@@ -0,0 +1,51 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github-setup-app/usecase"
+)
+
+// RunBackfill は backfill サブコマンドを実行し、終了コードを返す
+//
+//	backfill [-installation ID] [-filter GLOB] [-include-archived] [-concurrency N] [-dry-run] [-prune] [owner/repo ...]
+func RunBackfill(ctx context.Context, backfillUseCase *usecase.BackfillUseCase, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("backfill", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	var req usecase.BackfillRequest
+	fs.Int64Var(&req.InstallationID, "installation", 0, "set up every repository of this installation ID")
+	fs.StringVar(&req.Filter, "filter", "", "glob matched against the repository name (or owner/name)")
+	fs.BoolVar(&req.IncludeArchived, "include-archived", false, "also set up archived repositories")
+	fs.IntVar(&req.Concurrency, "concurrency", 4, "number of repositories set up in parallel")
+	fs.BoolVar(&req.DryRun, "dry-run", false, "print the planned changes as JSON without changing anything")
+	fs.BoolVar(&req.PruneLabels, "prune", false, "delete existing labels that are not in the profile (review them with -dry-run first)")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: backfill [flags] [owner/repo ...]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	req.Repositories = fs.Args()
+
+	report, err := backfillUseCase.Run(ctx, req)
+	if err != nil {
+		fmt.Fprintf(stderr, "backfill: %v\n", err)
+		return 1
+	}
+	for _, result := range report.Results {
+		printDeletions(stderr, "backfill", result)
+	}
+
+	if err := writeJSON(stdout, report); err != nil {
+		fmt.Fprintf(stderr, "backfill: %v\n", err)
+		return 1
+	}
+	if report.Failed > 0 {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// Usage はサブコマンドの一覧を出力する
+func Usage(w io.Writer) {
+	fmt.Fprintln(w, `Usage: github-setup-app <command> [flags] [args]
+
+Commands:
+  serve                       start the webhook server (default)
+  setup <owner/repo>          set up a repository now
+  plan <owner/repo>           print the changes setup would make, without changing anything
+  backfill [owner/repo ...]   set up existing repositories
+  labels sync <owner/repo>    sync labels with the setup profile
+  labels export <owner/repo>  print the labels of a repository as a profile snippet
+  validate-config             check the configuration, setup profile and rules
+
+Run "github-setup-app <command> -h" for the flags of each command.`)
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
@@ -0,0 +1,104 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github-setup-app/config"
+	"github-setup-app/usecase"
+)
+
+// RunLabels は labels サブコマンド（sync / export）を実行し、終了コードを返す
+//
+//	labels sync [-profile NAME] [-dry-run] owner/repo
+//	labels export [-format yaml|json] owner/repo
+func RunLabels(ctx context.Context, labelUseCase *usecase.LabelUseCase, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprintln(stderr, "Usage: labels <sync|export> [flags] owner/repo")
+		return 2
+	}
+
+	switch args[0] {
+	case "sync":
+		return runLabelsSync(ctx, labelUseCase, args[1:], stdout, stderr)
+	case "export":
+		return runLabelsExport(ctx, labelUseCase, args[1:], stdout, stderr)
+	default:
+		fmt.Fprintf(stderr, "labels: unknown command %q\n", args[0])
+		return 2
+	}
+}
+
+func runLabelsSync(ctx context.Context, labelUseCase *usecase.LabelUseCase, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("labels sync", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	profile := fs.String("profile", "", "profile to sync with (default: selected by the setup rules)")
+	dryRun := fs.Bool("dry-run", false, "print the planned operations without changing anything")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: labels sync [flags] owner/repo")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	plan, err := labelUseCase.Sync(ctx, fs.Arg(0), *profile, *dryRun)
+	if err != nil {
+		fmt.Fprintf(stderr, "labels sync: %v\n", err)
+		return 1
+	}
+	if err := writeJSON(stdout, plan); err != nil {
+		fmt.Fprintf(stderr, "labels sync: %v\n", err)
+		return 1
+	}
+	fmt.Fprintf(stderr, "%s\n", plan.Summary())
+	return 0
+}
+
+func runLabelsExport(ctx context.Context, labelUseCase *usecase.LabelUseCase, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("labels export", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	format := fs.String("format", "yaml", "output format: yaml (profile snippet) or json")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: labels export [flags] owner/repo")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	labels, err := labelUseCase.Export(ctx, fs.Arg(0))
+	if err != nil {
+		fmt.Fprintf(stderr, "labels export: %v\n", err)
+		return 1
+	}
+
+	switch *format {
+	case "yaml":
+		data, err := config.MarshalLabels(labels)
+		if err != nil {
+			fmt.Fprintf(stderr, "labels export: %v\n", err)
+			return 1
+		}
+		stdout.Write(data)
+	case "json":
+		if err := writeJSON(stdout, labels); err != nil {
+			fmt.Fprintf(stderr, "labels export: %v\n", err)
+			return 1
+		}
+	default:
+		fmt.Fprintf(stderr, "labels export: unknown format %q\n", *format)
+		return 2
+	}
+	return 0
+}
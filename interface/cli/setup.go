@@ -0,0 +1,83 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/usecase"
+)
+
+// RunSetup は setup サブコマンドを実行し、終了コードを返す
+//
+//	setup [-include-archived] [-prune] owner/repo
+func RunSetup(ctx context.Context, backfillUseCase *usecase.BackfillUseCase, args []string, stdout, stderr io.Writer) int {
+	return runSingle(ctx, "setup", backfillUseCase, false, args, stdout, stderr)
+}
+
+// RunPlan は plan サブコマンドを実行し、終了コードを返す。変更は行わずに予定の操作を JSON で出力する
+//
+//	plan [-include-archived] [-prune] owner/repo
+func RunPlan(ctx context.Context, backfillUseCase *usecase.BackfillUseCase, args []string, stdout, stderr io.Writer) int {
+	return runSingle(ctx, "plan", backfillUseCase, true, args, stdout, stderr)
+}
+
+// printDeletions はドライランで記録した削除（ラベル・ファイル）を、JSON の出力より先に stderr に表示する
+func printDeletions(stderr io.Writer, name string, result usecase.BackfillResult) {
+	for _, action := range result.Plan {
+		switch action.Type {
+		case entity.PlannedDeleteLabel:
+			fmt.Fprintf(stderr, "%s: %s: will delete label %q\n", name, result.Repository, action.Name)
+		case entity.PlannedDeleteFile:
+			fmt.Fprintf(stderr, "%s: %s: will delete file %s\n", name, result.Repository, action.Path)
+		}
+	}
+}
+
+func runSingle(ctx context.Context, name string, backfillUseCase *usecase.BackfillUseCase, dryRun bool, args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	req := usecase.BackfillRequest{Concurrency: 1, DryRun: dryRun}
+	fs.BoolVar(&req.IncludeArchived, "include-archived", false, "also process an archived repository")
+	fs.BoolVar(&req.PruneLabels, "prune", false, "delete existing labels that are not in the profile (review them with plan -prune first)")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: %s [flags] owner/repo\n", name)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	req.Repositories = fs.Args()
+
+	report, err := backfillUseCase.Run(ctx, req)
+	if err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+
+	result := report.Results[0]
+	printDeletions(stderr, name, result)
+	var out any = result
+	if dryRun {
+		out = result.Plan
+	}
+	if err := writeJSON(stdout, out); err != nil {
+		fmt.Fprintf(stderr, "%s: %v\n", name, err)
+		return 1
+	}
+
+	switch result.Status {
+	case usecase.BackfillFailed:
+		fmt.Fprintf(stderr, "%s: %s: %s\n", name, result.Repository, result.Error)
+		return 1
+	case usecase.BackfillSkipped:
+		fmt.Fprintf(stderr, "%s: %s: %s\n", name, result.Repository, result.Error)
+	}
+	return 0
+}
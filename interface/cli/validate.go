@@ -0,0 +1,74 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+
+	"github-setup-app/config"
+)
+
+// RunValidateConfig は validate-config サブコマンドを実行し、終了コードを返す。
+// GitHub にはアクセスせず、lookup（環境変数）と設定ファイルの設定、プロファイルとルールを読み込んで内容を表示する。
+// -setup-only の場合は App の ID や秘密鍵を検証せず、プロファイルとルールだけを確認する
+//
+//	validate-config [-config PATH] [-profile PATH] [-rules PATH] [-setup-only]
+func RunValidateConfig(args []string, lookup config.LookupFunc, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("validate-config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	configPath := fs.String("config", getenv(lookup, "CONFIG_FILE"), "config file path (default: $CONFIG_FILE)")
+	profilePath := fs.String("profile", "", "setup profile path (default: SETUP_PROFILE_PATH)")
+	rulesPath := fs.String("rules", "", "setup rules path (default: SETUP_RULES_PATH)")
+	setupOnly := fs.Bool("setup-only", false, "validate only the setup profile and rules")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	defaultProfile, defaultRules := getenv(lookup, "SETUP_PROFILE_PATH"), getenv(lookup, "SETUP_RULES_PATH")
+	if !*setupOnly {
+		cfg, err := config.Load(*configPath, lookup)
+		if err != nil {
+			fmt.Fprintf(stderr, "validate-config: invalid configuration:\n%v\n", err)
+			return 1
+		}
+		defaultProfile, defaultRules = cfg.SetupProfilePath, cfg.SetupRulesPath
+		fmt.Fprintf(stdout, "github app %d, label app %d, job store %s, delivery store %s, status store %s\n",
+			cfg.GitHubApp.ID, cfg.LabelApp.ID, cfg.JobStore, cfg.DeliveryStore, cfg.StatusStore)
+	}
+	if *profilePath == "" {
+		*profilePath = defaultProfile
+	}
+	if *rulesPath == "" {
+		*rulesPath = defaultRules
+	}
+
+	setup, err := config.LoadSetup(*profilePath, *rulesPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "validate-config: %v\n", err)
+		return 1
+	}
+
+	names := make([]string, 0, len(setup.Profiles))
+	for name := range setup.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		p := setup.Profiles[name]
+		license := "none"
+		if p.License != nil && p.License.Default != "" {
+			license = p.License.Default
+		}
+		fmt.Fprintf(stdout, "profile %s: %d files, %d labels, %d secrets, license %s, setup-labels workflow %t\n",
+			name, len(p.Files), len(p.Labels), len(p.Secrets), license, p.Workflows.SetupLabels)
+	}
+	fmt.Fprintf(stdout, "%d rules\n", len(setup.Rules.Rules))
+	fmt.Fprintln(stdout, "OK")
+	return 0
+}
+
+func getenv(lookup config.LookupFunc, key string) string {
+	v, _ := lookup(key)
+	return v
+}
@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github-setup-app/usecase"
+)
+
+// AdminHandler は運用者向けの API。ADMIN_TOKEN による Bearer 認証が必要
+type AdminHandler struct {
+	backfillUseCase *usecase.BackfillUseCase
+	jobQueue        *usecase.JobQueue
+	adminToken      string
+}
+
+// NewAdminHandler は AdminHandler を作成する。adminToken が空の場合は全てのリクエストを拒否する
+func NewAdminHandler(backfillUseCase *usecase.BackfillUseCase, jobQueue *usecase.JobQueue, adminToken string) *AdminHandler {
+	return &AdminHandler{
+		backfillUseCase: backfillUseCase,
+		jobQueue:        jobQueue,
+		adminToken:      adminToken,
+	}
+}
+
+// backfillRequest は BackfillRequest に加えて単一リポジトリの指定を受け付ける
+type backfillRequest struct {
+	usecase.BackfillRequest
+	Repository string `json:"repository"`
+}
+
+// Setup は POST /admin/setup で既存リポジトリのセットアップをジョブとして登録し、リポジトリごとのジョブ ID を返す。
+// 多数のリポジトリでもリクエストがタイムアウトせず、再起動しても失われない。
+// dry_run の場合は変更を行わないため、その場で実行して予定の操作を返す
+func (h *AdminHandler) Setup(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+
+	var req backfillRequest
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&req); err != nil {
+		http.Error(w, "Error parsing request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.Repository != "" {
+		req.Repositories = append(req.Repositories, req.Repository)
+	}
+	// ?dry_run=true でも指定できる
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		dryRun, err := strconv.ParseBool(v)
+		if err != nil {
+			http.Error(w, "Invalid dry_run: "+v, http.StatusBadRequest)
+			return
+		}
+		req.DryRun = req.DryRun || dryRun
+	}
+
+	if req.DryRun {
+		report, err := h.backfillUseCase.Run(r.Context(), req.BackfillRequest)
+		if err != nil {
+			slog.ErrorContext(r.Context(), "failed to run backfill", "error", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+		return
+	}
+
+	report, err := h.backfillUseCase.Enqueue(r.Context(), req.BackfillRequest, h.jobQueue)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to queue backfill", "error", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, report)
+}
+
+func (h *AdminHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	return authorizeAdmin(w, r, h.adminToken)
+}
+
+// RequireAdminToken は next の前に ADMIN_TOKEN による Bearer 認証を行う。adminToken が空の場合は全てのリクエストを拒否する
+func RequireAdminToken(adminToken string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if authorizeAdmin(w, r, adminToken) {
+			next(w, r)
+		}
+	}
+}
+
+func authorizeAdmin(w http.ResponseWriter, r *http.Request, adminToken string) bool {
+	if adminToken == "" {
+		http.Error(w, "Admin API is disabled", http.StatusForbidden)
+		return false
+	}
+
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(adminToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
@@ -1,7 +1,13 @@
 package handler
 
-import "net/http"
+import (
+	"log/slog"
+	"net/http"
 
+	"github-setup-app/usecase"
+)
+
+// HealthHandler はプロセスが応答できるかだけを返す（liveness probe 用）
 type HealthHandler struct{}
 
 func NewHealthHandler() *HealthHandler {
@@ -12,3 +18,28 @@ func (h *HealthHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("OK"))
 }
+
+// ReadyHandler は秘密鍵やジョブストアなど、処理に必要なものが使えるかを確認する（readiness probe 用）
+type ReadyHandler struct {
+	readinessUseCase *usecase.ReadinessUseCase
+}
+
+func NewReadyHandler(readinessUseCase *usecase.ReadinessUseCase) *ReadyHandler {
+	return &ReadyHandler{readinessUseCase: readinessUseCase}
+}
+
+// Handle は GET /ready で確認項目ごとの結果を返す。失敗した項目があれば 503
+func (h *ReadyHandler) Handle(w http.ResponseWriter, r *http.Request) {
+	report := h.readinessUseCase.Check(r.Context())
+
+	status := http.StatusOK
+	if !report.Ready {
+		status = http.StatusServiceUnavailable
+		for _, c := range report.Checks {
+			if !c.OK {
+				slog.WarnContext(r.Context(), "readiness check failed", "check", c.Name, "error", c.Error)
+			}
+		}
+	}
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,73 @@
+package handler
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"regexp"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/usecase"
+)
+
+// repoNamePattern は GitHub のオーナー名・リポジトリ名として有効な文字列
+var repoNamePattern = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+type StatusHandler struct {
+	statusUseCase *usecase.SetupStatusUseCase
+}
+
+func NewStatusHandler(statusUseCase *usecase.SetupStatusUseCase) *StatusHandler {
+	return &StatusHandler{statusUseCase: statusUseCase}
+}
+
+// Get は GET /status/{owner}/{repo} で最新の実行記録を返す
+func (h *StatusHandler) Get(w http.ResponseWriter, r *http.Request) {
+	owner, name := r.PathValue("owner"), r.PathValue("repo")
+	if !validRepoName(owner) || !validRepoName(name) {
+		http.Error(w, "Invalid repository", http.StatusBadRequest)
+		return
+	}
+
+	run, err := h.statusUseCase.Get(r.Context(), owner, name)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to load setup status", "error", err)
+		http.Error(w, "Error loading status", http.StatusInternalServerError)
+		return
+	}
+	if run == nil {
+		http.Error(w, "Not found", http.StatusNotFound)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, run)
+}
+
+// List は GET /status で実行記録の一覧を返す（?owner= と ?status= で絞り込み）
+func (h *StatusHandler) List(w http.ResponseWriter, r *http.Request) {
+	filter := usecase.SetupStatusFilter{
+		Owner:  r.URL.Query().Get("owner"),
+		Status: entity.SetupRunStatus(r.URL.Query().Get("status")),
+	}
+
+	runs, err := h.statusUseCase.List(r.Context(), filter)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "failed to list setup status", "error", err)
+		http.Error(w, "Error listing status", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"runs": runs})
+}
+
+func validRepoName(s string) bool {
+	return repoNamePattern.MatchString(s) && s != "." && s != ".."
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("failed to write response", "error", err)
+	}
+}
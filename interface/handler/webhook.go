@@ -7,24 +7,41 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/go-github/v57/github"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 
 	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+	"github-setup-app/logging"
+	"github-setup-app/metrics"
+	"github-setup-app/tracing"
 	"github-setup-app/usecase"
 )
 
 type WebhookHandler struct {
-	setupUseCase  *usecase.SetupRepositoryUseCase
-	webhookSecret string
+	jobQueue            *usecase.JobQueue
+	installationUseCase *usecase.InstallationUseCase
+	deliveryStore       repository.DeliveryStore
+	webhookSecret       string
+	// setupOnInstall が true の場合、インストール時に対象になったリポジトリもセットアップする
+	setupOnInstall bool
 }
 
-func NewWebhookHandler(setupUseCase *usecase.SetupRepositoryUseCase, webhookSecret string) *WebhookHandler {
+// NewWebhookHandler は WebhookHandler を作成する。deliveryStore が nil の場合は重複配信を検出しない
+func NewWebhookHandler(jobQueue *usecase.JobQueue, installationUseCase *usecase.InstallationUseCase, deliveryStore repository.DeliveryStore, webhookSecret string, setupOnInstall bool) *WebhookHandler {
 	return &WebhookHandler{
-		setupUseCase:  setupUseCase,
-		webhookSecret: webhookSecret,
+		jobQueue:            jobQueue,
+		installationUseCase: installationUseCase,
+		deliveryStore:       deliveryStore,
+		webhookSecret:       webhookSecret,
+		setupOnInstall:      setupOnInstall,
 	}
 }
 
@@ -34,9 +51,43 @@ func (h *WebhookHandler) Handle(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	eventType := r.Header.Get("X-GitHub-Event")
+	deliveryID := r.Header.Get("X-GitHub-Delivery")
+	// 以降のログとジョブに配信 ID とイベントの種類を付ける
+	ctx, span := tracing.Start(r.Context(), "WebhookHandler.Handle",
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("github.event", eventType),
+			attribute.String("github.delivery_id", deliveryID),
+		),
+	)
+	r = r.WithContext(logging.With(ctx, "delivery_id", deliveryID, "event", eventType))
+	ctx = r.Context()
+
+	// 配信の結果をイベントとアクションごとに数える。
+	// ラベルは署名を確認してから決め、既知の値以外は other にまとめる（任意の値で系列が増えないように）
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	w = rec
+	action := ""
+	metricEvent, metricAction := otherLabel, otherLabel
+	defer func() {
+		result := rec.result()
+		metrics.ObserveWebhook(metricEvent, metricAction, result)
+
+		span.SetAttributes(
+			attribute.String("github.action", action),
+			attribute.String("webhook.result", result),
+			attribute.Int("http.response.status_code", rec.status),
+		)
+		if rec.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(rec.status))
+		}
+		span.End()
+	}()
+
 	payload, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("Error reading body: %v", err)
+		slog.ErrorContext(ctx, "failed to read webhook body", "error", err)
 		http.Error(w, "Error reading body", http.StatusBadRequest)
 		return
 	}
@@ -44,28 +95,58 @@ func (h *WebhookHandler) Handle(w http.ResponseWriter, r *http.Request) {
 	if h.webhookSecret != "" {
 		signature := r.Header.Get("X-Hub-Signature-256")
 		if !h.verifySignature(payload, signature) {
-			log.Printf("Invalid signature")
+			slog.WarnContext(ctx, "invalid webhook signature")
 			http.Error(w, "Invalid signature", http.StatusUnauthorized)
 			return
 		}
 	}
+	action = payloadAction(payload)
+	metricEvent, metricAction = metricLabels(eventType, action)
 
-	eventType := r.Header.Get("X-GitHub-Event")
+	// 再配信された Webhook は処理しない
+	if h.deliveryStore != nil && deliveryID != "" {
+		isNew, err := h.deliveryStore.MarkSeen(ctx, deliveryID, time.Now())
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to record delivery", "error", err)
+			http.Error(w, "Error recording delivery", http.StatusInternalServerError)
+			return
+		}
+		if !isNew {
+			slog.InfoContext(ctx, "skipping duplicate delivery")
+			rec.duplicate = true
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("Duplicate delivery"))
+			return
+		}
+
+		// 処理に失敗した配信は記録を取り消し、再配信で処理できるようにする
+		defer func() {
+			if rec.status >= http.StatusInternalServerError {
+				if err := h.deliveryStore.Forget(ctx, deliveryID); err != nil {
+					slog.ErrorContext(ctx, "failed to forget delivery", "error", err)
+				}
+			}
+		}()
+	}
 
 	switch eventType {
 	case "repository":
-		h.handleRepositoryEvent(w, payload)
+		h.handleRepositoryEvent(w, r, payload)
 	case "workflow_run":
-		h.handleWorkflowRunEvent(w, payload)
+		h.handleWorkflowRunEvent(w, r, payload)
+	case "installation":
+		h.handleInstallationEvent(w, r, payload)
+	case "installation_repositories":
+		h.handleInstallationRepositoriesEvent(w, r, payload)
 	default:
 		w.WriteHeader(http.StatusOK)
 	}
 }
 
-func (h *WebhookHandler) handleRepositoryEvent(w http.ResponseWriter, payload []byte) {
+func (h *WebhookHandler) handleRepositoryEvent(w http.ResponseWriter, r *http.Request, payload []byte) {
 	var event github.RepositoryEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
-		log.Printf("Error parsing repository event: %v", err)
+		slog.WarnContext(r.Context(), "failed to parse repository event", "error", err)
 		http.Error(w, "Error parsing payload", http.StatusBadRequest)
 		return
 	}
@@ -80,22 +161,25 @@ func (h *WebhookHandler) handleRepositoryEvent(w http.ResponseWriter, payload []
 		Name:           event.GetRepo().GetName(),
 		InstallationID: event.GetInstallation().GetID(),
 	}
+	r = r.WithContext(withRepository(r.Context(), repo))
 
-	go func() {
-		ctx := context.Background()
-		if err := h.setupUseCase.Execute(ctx, repo); err != nil {
-			log.Printf("Error setting up repository: %v", err)
-		}
-	}()
+	// 作成時のペイロードには template_repository が含まれず topics も未設定のことが多いため、
+	// プロファイルはジョブの実行時に API から属性を取得して選ぶ（セットアップしない場合もある）。
+	// 保存に失敗した場合は 500 を返し、GitHub 側で再配信できるようにする
+	if _, err := h.jobQueue.EnqueueSetup(r.Context(), repo, ""); err != nil {
+		slog.ErrorContext(r.Context(), "failed to enqueue setup job", "error", err)
+		http.Error(w, "Error enqueueing job", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Processing"))
 }
 
-func (h *WebhookHandler) handleWorkflowRunEvent(w http.ResponseWriter, payload []byte) {
+func (h *WebhookHandler) handleWorkflowRunEvent(w http.ResponseWriter, r *http.Request, payload []byte) {
 	var event github.WorkflowRunEvent
 	if err := json.Unmarshal(payload, &event); err != nil {
-		log.Printf("Error parsing workflow_run event: %v", err)
+		slog.WarnContext(r.Context(), "failed to parse workflow_run event", "error", err)
 		http.Error(w, "Error parsing payload", http.StatusBadRequest)
 		return
 	}
@@ -107,9 +191,10 @@ func (h *WebhookHandler) handleWorkflowRunEvent(w http.ResponseWriter, payload [
 	}
 
 	if event.GetWorkflowRun().GetConclusion() != "success" {
-		log.Printf("Workflow %s completed with conclusion: %s, skipping deletion",
-			event.GetWorkflowRun().GetName(),
-			event.GetWorkflowRun().GetConclusion())
+		slog.InfoContext(r.Context(), "workflow did not succeed, skipping deletion",
+			"workflow", event.GetWorkflowRun().GetName(),
+			"conclusion", event.GetWorkflowRun().GetConclusion(),
+			"repository", event.GetRepo().GetFullName())
 		w.WriteHeader(http.StatusOK)
 		return
 	}
@@ -125,20 +210,104 @@ func (h *WebhookHandler) handleWorkflowRunEvent(w http.ResponseWriter, payload [
 		Name:           event.GetRepo().GetName(),
 		InstallationID: event.GetInstallation().GetID(),
 	}
+	r = r.WithContext(withRepository(r.Context(), repo))
 
-	go func() {
-		ctx := context.Background()
-		if err := h.setupUseCase.DeleteWorkflow(ctx, repo); err != nil {
-			log.Printf("Error deleting workflow file: %v", err)
-		} else {
-			log.Printf("Successfully deleted workflow file: %s/%s", repo.Owner, repo.Name)
-		}
-	}()
+	if _, err := h.jobQueue.EnqueueDeleteWorkflow(r.Context(), repo); err != nil {
+		slog.ErrorContext(r.Context(), "failed to enqueue workflow deletion job", "error", err)
+		http.Error(w, "Error enqueueing job", http.StatusInternalServerError)
+		return
+	}
 
 	w.WriteHeader(http.StatusOK)
 	w.Write([]byte("Processing workflow deletion"))
 }
 
+func (h *WebhookHandler) handleInstallationEvent(w http.ResponseWriter, r *http.Request, payload []byte) {
+	var event github.InstallationEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		slog.WarnContext(r.Context(), "failed to parse installation event", "error", err)
+		http.Error(w, "Error parsing payload", http.StatusBadRequest)
+		return
+	}
+
+	installationID := event.GetInstallation().GetID()
+	r = r.WithContext(logging.With(r.Context(), "installation_id", installationID))
+
+	switch event.GetAction() {
+	case "created":
+		slog.InfoContext(r.Context(), "app installed", "account", event.GetInstallation().GetAccount().GetLogin())
+		h.enqueueInstalledRepositories(w, r, installationID, event.Repositories)
+	case "deleted":
+		// アンインストールされた installation の状態を削除する
+		slog.InfoContext(r.Context(), "app uninstalled")
+		if err := h.installationUseCase.Purge(r.Context(), installationID); err != nil {
+			slog.ErrorContext(r.Context(), "failed to purge installation", "error", err)
+			http.Error(w, "Error purging installation", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (h *WebhookHandler) handleInstallationRepositoriesEvent(w http.ResponseWriter, r *http.Request, payload []byte) {
+	var event github.InstallationRepositoriesEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		slog.WarnContext(r.Context(), "failed to parse installation_repositories event", "error", err)
+		http.Error(w, "Error parsing payload", http.StatusBadRequest)
+		return
+	}
+
+	if event.GetAction() != "added" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	installationID := event.GetInstallation().GetID()
+	r = r.WithContext(logging.With(r.Context(), "installation_id", installationID))
+	h.enqueueInstalledRepositories(w, r, installationID, event.RepositoriesAdded)
+}
+
+// enqueueInstalledRepositories は setupOnInstall が有効な場合にインストール対象のリポジトリのセットアップを登録する
+func (h *WebhookHandler) enqueueInstalledRepositories(w http.ResponseWriter, r *http.Request, installationID int64, repos []*github.Repository) {
+	if !h.setupOnInstall {
+		slog.InfoContext(r.Context(), "setup on install is disabled, skipping repositories", "count", len(repos))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	for _, ghRepo := range repos {
+		// インストールイベントのリポジトリには owner が含まれないため full_name から取得する
+		owner, name, ok := strings.Cut(ghRepo.GetFullName(), "/")
+		if !ok {
+			slog.WarnContext(r.Context(), "skipping repository with invalid full_name", "full_name", ghRepo.GetFullName())
+			continue
+		}
+
+		repo := entity.Repository{
+			Owner:          owner,
+			Name:           name,
+			InstallationID: installationID,
+		}
+		// インストールイベントには fork などの属性が含まれないため、プロファイルは実行時に選ぶ
+		ctx := withRepository(r.Context(), repo)
+		if _, err := h.jobQueue.EnqueueSetup(ctx, repo, ""); err != nil {
+			slog.ErrorContext(ctx, "failed to enqueue setup job", "error", err)
+			http.Error(w, "Error enqueueing job", http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("Processing"))
+}
+
+// withRepository はログにリポジトリと installation を付ける
+func withRepository(ctx context.Context, repo entity.Repository) context.Context {
+	return logging.With(ctx, "repository", repo.FullName(), "installation_id", repo.InstallationID)
+}
+
 func (h *WebhookHandler) verifySignature(payload []byte, signature string) bool {
 	if len(signature) < 7 || signature[:7] != "sha256=" {
 		return false
@@ -150,3 +319,69 @@ func (h *WebhookHandler) verifySignature(payload []byte, signature string) bool
 
 	return hmac.Equal([]byte(signature[7:]), []byte(expectedMAC))
 }
+
+// payloadAction はペイロードの action を返す。action がないイベントは空
+func payloadAction(payload []byte) string {
+	var event struct {
+		Action string `json:"action"`
+	}
+	json.Unmarshal(payload, &event)
+	return event.Action
+}
+
+// otherLabel は metricLabels の許可リストにないイベントとアクションをまとめるラベル
+const otherLabel = "other"
+
+// metricEvents は処理するイベントと、メトリクスで区別するそのアクション
+var metricEvents = map[string][]string{
+	"ping":                      nil,
+	"repository":                {"created"},
+	"workflow_run":              {"completed"},
+	"installation":              {"created", "deleted"},
+	"installation_repositories": {"added", "removed"},
+}
+
+// metricLabels は Webhook のメトリクスのラベルを返す。許可リストにない値は other にする
+func metricLabels(event, action string) (string, string) {
+	actions, ok := metricEvents[event]
+	if !ok {
+		return otherLabel, otherLabel
+	}
+	if action == "" {
+		return event, ""
+	}
+	for _, a := range actions {
+		if a == action {
+			return event, action
+		}
+	}
+	return event, otherLabel
+}
+
+// statusRecorder はハンドラーが返したステータスコードを記録する
+type statusRecorder struct {
+	http.ResponseWriter
+	status    int
+	duplicate bool
+}
+
+// result はメトリクスに使う配信の結果を返す
+func (r *statusRecorder) result() string {
+	switch {
+	case r.duplicate:
+		return "duplicate"
+	case r.status == http.StatusUnauthorized:
+		return "invalid_signature"
+	case r.status >= http.StatusInternalServerError:
+		return "error"
+	case r.status >= http.StatusBadRequest:
+		return "rejected"
+	default:
+		return "accepted"
+	}
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,111 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+)
+
+// New は level（debug / info / warn / error）と format（text / json）に従ってロガーを作成する。
+// ロガーは With で context に付けた属性を全てのログに出力する
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	var lv slog.Level
+	if level != "" {
+		if err := lv.UnmarshalText([]byte(level)); err != nil {
+			return nil, fmt.Errorf("invalid log level %q: %w", level, err)
+		}
+	}
+	opts := &slog.HandlerOptions{Level: lv}
+
+	var h slog.Handler
+	switch strings.ToLower(format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("invalid log format %q (must be text or json)", format)
+	}
+
+	return slog.New(contextHandler{Handler: h}), nil
+}
+
+type attrsKey struct{}
+
+// With は ctx を使って出力するログに args の属性を追加する（slog.Logger.With と同じ形式）。
+// 同じキーの属性が既にある場合は置き換える
+func With(ctx context.Context, args ...any) context.Context {
+	attrs := attrsFrom(ctx)
+	for _, a := range argsToAttrs(args) {
+		i := slices.IndexFunc(attrs, func(b slog.Attr) bool { return b.Key == a.Key })
+		if i >= 0 {
+			attrs[i] = a
+		} else {
+			attrs = append(attrs, a)
+		}
+	}
+	return context.WithValue(ctx, attrsKey{}, attrs)
+}
+
+// Attr は ctx に付けた属性 key の値を返す
+func Attr(ctx context.Context, key string) (slog.Value, bool) {
+	for _, a := range attrsFrom(ctx) {
+		if a.Key == key {
+			return a.Value, true
+		}
+	}
+	return slog.Value{}, false
+}
+
+func attrsFrom(ctx context.Context) []slog.Attr {
+	attrs, _ := ctx.Value(attrsKey{}).([]slog.Attr)
+	// append で元のスライスを書き換えないようにコピーする
+	return append([]slog.Attr(nil), attrs...)
+}
+
+func argsToAttrs(args []any) []slog.Attr {
+	var r slog.Record
+	r.Add(args...)
+	attrs := make([]slog.Attr, 0, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+	return attrs
+}
+
+// contextHandler は context の属性をレコードに追加する。レコードに同じキーがある場合はレコードを優先する
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	attrs := attrsFrom(ctx)
+	if len(attrs) == 0 {
+		return h.Handler.Handle(ctx, r)
+	}
+
+	keys := make(map[string]bool, r.NumAttrs())
+	r.Attrs(func(a slog.Attr) bool {
+		keys[a.Key] = true
+		return true
+	})
+	r = r.Clone()
+	for _, a := range attrs {
+		if !keys[a.Key] {
+			r.AddAttrs(a)
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -1,96 +1,369 @@
 package main
 
 import (
-	"encoding/base64"
+	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/joho/godotenv"
 
+	"github-setup-app/config"
+	"github-setup-app/domain/repository"
 	"github-setup-app/infrastructure/github"
+	"github-setup-app/infrastructure/keyfile"
+	"github-setup-app/infrastructure/store"
+	"github-setup-app/interface/cli"
 	"github-setup-app/interface/handler"
+	"github-setup-app/logging"
+	"github-setup-app/metrics"
+	"github-setup-app/tracing"
 	"github-setup-app/usecase"
 )
 
 func main() {
 	// .env を読み込んでローカル・Docker双方で同じ挙動にする
-	if err := godotenv.Load(); err != nil {
-		log.Printf("Warning: could not load .env file: %v", err)
+	envErr := godotenv.Load()
+
+	// サブコマンド（省略時は serve）。-h / --help はフラグの形でもヘルプとして扱う
+	command, args := "serve", os.Args[1:]
+	if len(args) > 0 && (!strings.HasPrefix(args[0], "-") || isHelpFlag(args[0])) {
+		command, args = args[0], args[1:]
 	}
 
-	// 環境変数の読み込み
-	port := os.Getenv("PORT")
-	if port == "" {
-		port = "8080"
+	switch command {
+	case "serve", "setup", "plan", "backfill", "labels":
+	case "validate-config":
+		os.Exit(cli.RunValidateConfig(args, os.LookupEnv, os.Stdout, os.Stderr))
+	case "help", "-h", "-help", "--help":
+		cli.Usage(os.Stdout)
+		return
+	default:
+		cli.Usage(os.Stderr)
+		os.Exit(2)
 	}
 
-	// このGitHub App（リポジトリ操作用）
-	appIDStr := os.Getenv("GITHUB_APP_ID")
-	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	// 環境変数（.env を含む）と CONFIG_FILE の設定ファイルを読み込み、全ての誤りをまとめて表示する
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"), os.LookupEnv)
 	if err != nil {
-		log.Fatalf("Invalid GITHUB_APP_ID: %v", err)
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%v\n", err)
+		os.Exit(1)
+	}
+
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+	if envErr != nil {
+		slog.Warn("could not load .env file", "error", envErr)
 	}
 
-	privateKeyEnv := os.Getenv("GITHUB_PRIVATE_KEY")
-	if privateKeyEnv == "" {
-		log.Fatal("GITHUB_PRIVATE_KEY is required")
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.TracesExporter, os.Stderr)
+	if err != nil {
+		fatalf("Failed to set up tracing: %v", err)
+	}
+
+	a := newApp(cfg)
+	var code int
+	if command == "serve" {
+		code = serve(a, cfg)
+	} else {
+		code = runCommand(a, command, args)
+	}
+
+	// 終了前に残りのスパンを送信する
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(ctx); err != nil {
+		slog.Error("failed to flush traces", "error", err)
+	}
+	os.Exit(code)
+}
+
+// app はサーバーとサブコマンドで共有する依存関係
+type app struct {
+	githubClient    *github.GitHubClient
+	runStore        repository.SetupRunStore
+	setupUseCase    *usecase.SetupRepositoryUseCase
+	statusUseCase   *usecase.SetupStatusUseCase
+	profileRouter   *usecase.ProfileRouter
+	backfillUseCase *usecase.BackfillUseCase
+	labelUseCase    *usecase.LabelUseCase
+
+	// ラベル操作App の ID と秘密鍵（/ready で確認する）
+	labelAppID int64
+	labelKey   repository.PrivateKeyProvider
+}
+
+// runCommand は GitHub にアクセスするサブコマンドを実行し、終了コードを返す
+func runCommand(a *app, command string, args []string) int {
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	switch command {
+	case "setup":
+		return cli.RunSetup(ctx, a.backfillUseCase, args, os.Stdout, os.Stderr)
+	case "plan":
+		return cli.RunPlan(ctx, a.backfillUseCase, args, os.Stdout, os.Stderr)
+	case "backfill":
+		return cli.RunBackfill(ctx, a.backfillUseCase, args, os.Stdout, os.Stderr)
+	case "labels":
+		return cli.RunLabels(ctx, a.labelUseCase, args, os.Stdout, os.Stderr)
 	}
-	privateKeyEnv = strings.TrimSpace(privateKeyEnv)
-	privateKeyEnv = strings.ReplaceAll(privateKeyEnv, "\r\n", "\n")
-	privateKeyEnv = strings.ReplaceAll(privateKeyEnv, `\n`, "\n")
-	privateKey := []byte(privateKeyEnv)
-	if !strings.Contains(privateKeyEnv, "BEGIN") || !strings.Contains(privateKeyEnv, "PRIVATE KEY") {
-		decoded, err := base64.StdEncoding.DecodeString(privateKeyEnv)
+	return 2
+}
+
+func isHelpFlag(arg string) bool {
+	return arg == "-h" || arg == "-help" || arg == "--help"
+}
+
+// newApp は設定から共通の依存関係を作成する
+func newApp(cfg *config.Config) *app {
+	// セットアッププロファイル（未指定なら組み込みのデフォルト）と、
+	// リポジトリの属性でプロファイルを選ぶルール（未指定なら全て default プロファイル）
+	setup, err := config.LoadSetup(cfg.SetupProfilePath, cfg.SetupRulesPath)
+	if err != nil {
+		fatalf("Invalid setup configuration: %v", err)
+	}
+	slog.Info("loaded setup configuration", "profile", setup.Profile.Name, "profiles", len(setup.Profiles), "rules", len(setup.Rules.Rules))
+
+	// 秘密鍵（*_PRIVATE_KEY_FILE の場合はファイルの変更に追従して入れ替える）
+	githubKey := privateKeyProvider(cfg.GitHubApp, cfg.KeyReloadInterval)
+	labelKey := privateKeyProvider(cfg.LabelApp, cfg.KeyReloadInterval)
+
+	// Infrastructure
+	githubClient := github.NewGitHubClient(cfg.GitHubApp.ID, githubKey,
+		github.WithRetryPolicy(retryPolicy(cfg.Retry)),
+		github.WithClientCacheSize(cfg.ClientCacheSize),
+	)
+
+	// セットアップの実行記録（STATUS_STORE=memory の場合は再起動で失われる）
+	var runStore repository.SetupRunStore
+	switch cfg.StatusStore {
+	case "file":
+		runStore, err = store.NewFileSetupRunStore(cfg.StatusStoreDir)
 		if err != nil {
-			log.Fatal("GITHUB_PRIVATE_KEY must be PEM or base64 encoded PEM content")
+			fatalf("Invalid STATUS_STORE_DIR: %v", err)
 		}
-		text := strings.TrimSpace(string(decoded))
-		text = strings.ReplaceAll(text, "\r\n", "\n")
-		privateKey = []byte(text)
+	case "memory":
+		runStore = store.NewMemorySetupRunStore()
+	}
+
+	// UseCase (シークレット登録のためラベル操作App の ID と秘密鍵を渡す)
+	labelAppID := strconv.FormatInt(cfg.LabelApp.ID, 10)
+	setupUseCase := usecase.NewSetupRepositoryUseCase(githubClient, runStore, labelAppID, labelKey, setup.Profile)
+	statusUseCase := usecase.NewSetupStatusUseCase(runStore)
+	profileRouter := usecase.NewProfileRouter(githubClient, setup.Profiles, setup.Rules)
+	newRecorder := func(base repository.GitHubRepository) repository.GitHubRecorder {
+		return github.NewDryRunClient(base)
+	}
+	backfillUseCase := usecase.NewBackfillUseCase(githubClient, setupUseCase, profileRouter, newRecorder)
+
+	labelUseCase := usecase.NewLabelUseCase(githubClient, setupUseCase, profileRouter)
+
+	return &app{
+		githubClient:    githubClient,
+		runStore:        runStore,
+		setupUseCase:    setupUseCase,
+		statusUseCase:   statusUseCase,
+		profileRouter:   profileRouter,
+		backfillUseCase: backfillUseCase,
+		labelUseCase:    labelUseCase,
+		labelAppID:      cfg.LabelApp.ID,
+		labelKey:        labelKey,
+	}
+}
+
+// privateKeyProvider は秘密鍵をファイルから読み込んだ場合は変更を監視する Watcher を、それ以外は固定の鍵を返す
+func privateKeyProvider(app config.App, interval time.Duration) repository.PrivateKeyProvider {
+	if app.PrivateKeyFile == "" {
+		return keyfile.Static(app.PrivateKey)
 	}
 
-	// ラベル操作専用GitHub App
-	labelAppIDStr := os.Getenv("LABEL_APP_ID")
-	if labelAppIDStr == "" {
-		log.Fatal("LABEL_APP_ID is required")
+	watcher, err := keyfile.NewWatcher(app.PrivateKeyFile, config.NormalizePrivateKey)
+	if err != nil {
+		fatalf("Failed to load private key: %v", err)
 	}
+	go watcher.Run(context.Background(), interval)
+	return watcher
+}
 
-	labelPrivateKeyEnv := os.Getenv("LABEL_PRIVATE_KEY")
-	if labelPrivateKeyEnv == "" {
-		log.Fatal("LABEL_PRIVATE_KEY is required")
+// retryPolicy は既定の再試行方針に設定で指定した項目を上書きする
+func retryPolicy(r config.Retry) github.RetryPolicy {
+	policy := github.DefaultRetryPolicy()
+	if r.MaxAttempts > 0 {
+		policy.MaxAttempts = r.MaxAttempts
+	}
+	if r.InitialBackoff > 0 {
+		policy.InitialBackoff = r.InitialBackoff
 	}
-	labelPrivateKeyEnv = strings.TrimSpace(labelPrivateKeyEnv)
-	labelPrivateKeyEnv = strings.ReplaceAll(labelPrivateKeyEnv, "\r\n", "\n")
-	labelPrivateKeyEnv = strings.ReplaceAll(labelPrivateKeyEnv, `\n`, "\n")
-	if !strings.Contains(labelPrivateKeyEnv, "BEGIN") || !strings.Contains(labelPrivateKeyEnv, "PRIVATE KEY") {
-		decoded, err := base64.StdEncoding.DecodeString(labelPrivateKeyEnv)
+	if r.MaxBackoff > 0 {
+		policy.MaxBackoff = r.MaxBackoff
+	}
+	if r.MaxRateLimitWait > 0 {
+		policy.MaxRateLimitWait = r.MaxRateLimitWait
+	}
+	if r.RetryNotFound != nil {
+		policy.RetryNotFound = *r.RetryNotFound
+	}
+	return policy
+}
+
+// serve は Webhook サーバーとジョブキューを起動し、シグナルを受けるまで動かす。
+// 終了コードを返す（サーバーが異常終了した場合も、ジョブキューを止めてから返す）
+func serve(a *app, cfg *config.Config) int {
+	var err error
+
+	// ジョブキュー（JOB_STORE=memory の場合は再起動でジョブが失われる）
+	var jobStore repository.JobStore
+	switch cfg.JobStore {
+	case "file":
+		jobStore, err = store.NewFileJobStore(cfg.JobStoreDir)
 		if err != nil {
-			log.Fatal("LABEL_PRIVATE_KEY must be PEM or base64 encoded PEM content")
+			fatalf("Invalid JOB_STORE_DIR: %v", err)
 		}
-		text := strings.TrimSpace(string(decoded))
-		text = strings.ReplaceAll(text, "\r\n", "\n")
-		labelPrivateKeyEnv = text
+	case "memory":
+		jobStore = store.NewMemoryJobStore()
 	}
 
-	webhookSecret := os.Getenv("WEBHOOK_SECRET")
+	jobQueue := usecase.NewJobQueue(jobStore, a.setupUseCase, a.profileRouter, cfg.JobWorkers, cfg.JobFailedRetention)
+	if err := jobQueue.Start(context.Background()); err != nil {
+		fatalf("Failed to start job queue: %v", err)
+	}
+	if err := metrics.RegisterQueueDepth(func(ctx context.Context) (map[string]int, error) {
+		counts, err := jobQueue.Depth(ctx)
+		if err != nil {
+			return nil, err
+		}
+		depth := make(map[string]int, len(counts))
+		for status, n := range counts {
+			depth[string(status)] = n
+		}
+		return depth, nil
+	}); err != nil {
+		fatalf("Failed to register job queue metrics: %v", err)
+	}
 
-	// Infrastructure
-	githubClient := github.NewGitHubClient(appID, privateKey)
+	// 重複配信の検出（DELIVERY_STORE=file の場合は再起動後も記録を保持する）
+	var deliveryStore repository.DeliveryStore
+	switch cfg.DeliveryStore {
+	case "memory":
+		deliveryStore = store.NewMemoryDeliveryStore(cfg.DeliveryTTL, cfg.DeliveryMaxEntries)
+	case "file":
+		deliveryStore, err = store.NewFileDeliveryStore(cfg.DeliveryStorePath, cfg.DeliveryTTL, cfg.DeliveryMaxEntries)
+		if err != nil {
+			fatalf("Invalid DELIVERY_STORE_PATH: %v", err)
+		}
+	}
 
-	// UseCase (シークレット登録のため labelAppIDStr と labelPrivateKeyEnv を渡す)
-	setupUseCase := usecase.NewSetupRepositoryUseCase(githubClient, labelAppIDStr, labelPrivateKeyEnv)
+	installationUseCase := usecase.NewInstallationUseCase(jobStore, a.runStore)
 
 	// Handler
-	webhookHandler := handler.NewWebhookHandler(setupUseCase, webhookSecret)
+	webhookHandler := handler.NewWebhookHandler(jobQueue, installationUseCase, deliveryStore, cfg.WebhookSecret, cfg.SetupOnInstall)
 	healthHandler := handler.NewHealthHandler()
+	readyHandler := handler.NewReadyHandler(newReadinessUseCase(a, jobQueue))
+	statusHandler := handler.NewStatusHandler(a.statusUseCase)
+	adminHandler := handler.NewAdminHandler(a.backfillUseCase, jobQueue, cfg.AdminToken)
 
 	// Router
-	http.HandleFunc("/webhook", webhookHandler.Handle)
-	http.HandleFunc("/health", healthHandler.Handle)
+	mux := http.NewServeMux()
+	mux.HandleFunc("/webhook", webhookHandler.Handle)
+	mux.HandleFunc("/health", healthHandler.Handle)
+	mux.HandleFunc("GET /ready", readyHandler.Handle)
+	mux.Handle("GET /metrics", metrics.Handler())
+	// 実行記録には非公開リポジトリの名前やエラーが含まれるため管理 API と同じ認証を求める
+	mux.HandleFunc("GET /status", handler.RequireAdminToken(cfg.AdminToken, statusHandler.List))
+	mux.HandleFunc("GET /status/{owner}/{repo}", handler.RequireAdminToken(cfg.AdminToken, statusHandler.Get))
+	mux.HandleFunc("POST /admin/setup", adminHandler.Setup)
+
+	// 終了の期限を過ぎても残っているリクエスト（ドライランなど）はこのコンテキストで中断する
+	requestCtx, cancelRequests := context.WithCancel(context.Background())
+	defer cancelRequests()
+	server := &http.Server{
+		Addr:              ":" + cfg.Port,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext:       func(net.Listener) context.Context { return requestCtx },
+	}
+
+	// SIGTERM / SIGINT を受けたら新しい Webhook の受付を止めてからジョブの完了を待つ
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("server starting", "port", cfg.Port)
+		serverErr <- server.ListenAndServe()
+	}()
+
+	code := 0
+	select {
+	case err := <-serverErr:
+		slog.Error("server error", "error", err)
+		code = 1
+	case <-ctx.Done():
+		stop()
+	}
+
+	// 新しいジョブの取得をすぐに止め、処理中のリクエストと実行中のジョブを並行して待つ。
+	// 全体で SHUTDOWN_TIMEOUT を超えないようにする（オーケストレーターの猶予期間内に終える）
+	slog.Info("shutting down", "timeout", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		if err := jobQueue.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shut down job queue", "error", err)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			slog.Error("failed to shut down server", "error", err)
+			cancelRequests()
+		}
+	}()
+	wg.Wait()
+	slog.Info("shutdown complete")
+	return code
+}
+
+// newReadinessUseCase は /ready で確認する項目を登録する
+func newReadinessUseCase(a *app, jobQueue *usecase.JobQueue) *usecase.ReadinessUseCase {
+	return usecase.NewReadinessUseCase(
+		usecase.ReadinessCheck{Name: "github_app_key", Check: func(ctx context.Context) error {
+			_, err := a.githubClient.AppJWT(time.Now())
+			return err
+		}},
+		usecase.ReadinessCheck{Name: "label_app_key", Check: func(ctx context.Context) error {
+			_, err := github.MintAppJWT(a.labelAppID, a.labelKey.PrivateKey(), time.Now())
+			return err
+		}},
+		usecase.ReadinessCheck{Name: "job_store", Check: func(ctx context.Context) error {
+			_, err := jobQueue.Depth(ctx)
+			return err
+		}},
+		usecase.ReadinessCheck{Name: "job_workers", Check: func(ctx context.Context) error {
+			return jobQueue.CheckWorkers()
+		}},
+	)
+}
 
-	log.Printf("Server starting on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+// fatalf は起動時の設定エラーを error レベルで出力して終了する
+func fatalf(format string, args ...any) {
+	slog.Error(fmt.Sprintf(format, args...))
+	os.Exit(1)
 }
@@ -0,0 +1,143 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "github_setup"
+
+// registry はこのアプリのメトリクスだけを登録する（プロセスと Go ランタイムのメトリクスを含む）
+var registry = prometheus.NewRegistry()
+
+var (
+	webhookDeliveries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_deliveries_total",
+		Help:      "Webhook deliveries by event, action and result.",
+	}, []string{"event", "action", "result"})
+
+	setupRuns = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "setup_runs_total",
+		Help:      "Setup runs by outcome and the step that failed.",
+	}, []string{"outcome", "failed_step"})
+
+	githubRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "github_api_request_duration_seconds",
+		Help:      "GitHub API request latency by GitHubClient method and status code.",
+		Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30},
+	}, []string{"method", "code"})
+
+	githubRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "github_api_retries_total",
+		Help:      "GitHub API retries by GitHubClient method and error class.",
+	}, []string{"method", "class"})
+
+	githubClientCache = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "github_client_cache_lookups_total",
+		Help:      "Installation client cache lookups by result.",
+	}, []string{"result"})
+
+	githubClientCacheEvictions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "github_client_cache_evictions_total",
+		Help:      "Installation clients evicted from the cache by reason.",
+	}, []string{"reason"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		webhookDeliveries,
+		setupRuns,
+		githubRequestDuration,
+		githubRetries,
+		githubClientCache,
+		githubClientCacheEvictions,
+	)
+}
+
+// Handler は /metrics で返す Prometheus 形式のハンドラーを返す
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{Registry: registry})
+}
+
+// ObserveWebhook は Webhook の配信を1件数える
+func ObserveWebhook(event, action, result string) {
+	webhookDeliveries.WithLabelValues(event, action, result).Inc()
+}
+
+// ObserveSetupRun は終了したセットアップを1件数える。成功した場合 failedStep は空
+func ObserveSetupRun(outcome, failedStep string) {
+	setupRuns.WithLabelValues(outcome, failedStep).Inc()
+}
+
+// ObserveGitHubRequest は GitHub API へのリクエスト1回の所要時間を記録する。
+// レスポンスがない（通信エラー）場合の status は 0
+func ObserveGitHubRequest(method string, status int, d time.Duration) {
+	code := "error"
+	if status > 0 {
+		code = strconv.Itoa(status)
+	}
+	githubRequestDuration.WithLabelValues(method, code).Observe(d.Seconds())
+}
+
+// ObserveGitHubRetry は GitHub API の再試行を1回数える
+func ObserveGitHubRetry(method, class string) {
+	githubRetries.WithLabelValues(method, class).Inc()
+}
+
+// ObserveClientCache はインストールごとのクライアントキャッシュの参照を1回数える（hit / miss / key_rotated）
+func ObserveClientCache(result string) {
+	githubClientCache.WithLabelValues(result).Inc()
+}
+
+// ObserveClientCacheEviction はキャッシュから追い出したクライアントを1件数える（capacity / expired）
+func ObserveClientCacheEviction(reason string) {
+	githubClientCacheEvictions.WithLabelValues(reason).Inc()
+}
+
+// RegisterQueueDepth はスクレイプのたびに count を呼び出し、ステータスごとのジョブ数を公開する
+func RegisterQueueDepth(count func(ctx context.Context) (map[string]int, error)) error {
+	return registry.Register(&queueDepthCollector{count: count})
+}
+
+var queueDepthDesc = prometheus.NewDesc(
+	prometheus.BuildFQName(namespace, "", "job_queue_depth"),
+	"Jobs in the job store by status.",
+	[]string{"status"}, nil,
+)
+
+// queueDepthCollector はジョブストアの件数をスクレイプ時に取得する
+type queueDepthCollector struct {
+	count func(ctx context.Context) (map[string]int, error)
+}
+
+func (c *queueDepthCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- queueDepthDesc
+}
+
+func (c *queueDepthCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(queueDepthDesc, err)
+		return
+	}
+	for status, n := range counts {
+		ch <- prometheus.MustNewConstMetric(queueDepthDesc, prometheus.GaugeValue, float64(n), status)
+	}
+}
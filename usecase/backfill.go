@@ -0,0 +1,331 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+	"github-setup-app/logging"
+)
+
+const defaultBackfillConcurrency = 4
+
+// BackfillRequest は既存リポジトリに対するセットアップの対象
+type BackfillRequest struct {
+	// Repositories は owner/name 形式で指定したリポジトリ
+	Repositories []string `json:"repositories"`
+	// InstallationID を指定すると、そのインストールの全リポジトリを対象にする
+	InstallationID int64 `json:"installation_id"`
+	// Filter はリポジトリ名（または owner/name）に対する glob。空なら全て
+	Filter          string `json:"filter"`
+	IncludeArchived bool   `json:"include_archived"`
+	// Concurrency は同時に実行するセットアップの数（Run のみ。Enqueue ではジョブキューのワーカー数に従う）
+	Concurrency int `json:"concurrency"`
+	// DryRun が true の場合は変更を行わず、実行する予定の操作を結果の Plan に返す
+	DryRun bool `json:"dry_run"`
+	// PruneLabels が true の場合、プロファイルにない既存ラベルを削除する（プロファイルの prune_labels が false なら削除しない）。
+	// 既存の Issue や PR からラベルが外れるため、指定しない場合はプロファイルの backfill_prune_labels に従う
+	PruneLabels bool `json:"prune_labels"`
+}
+
+type BackfillResultStatus string
+
+const (
+	BackfillSucceeded BackfillResultStatus = "succeeded"
+	BackfillFailed    BackfillResultStatus = "failed"
+	BackfillSkipped   BackfillResultStatus = "skipped"
+	// BackfillPlanned はドライランで操作を記録できた
+	BackfillPlanned BackfillResultStatus = "planned"
+	// BackfillQueued はジョブキューに登録した（結果は /status で確認する）
+	BackfillQueued BackfillResultStatus = "queued"
+)
+
+type BackfillResult struct {
+	Repository string               `json:"repository"`
+	Status     BackfillResultStatus `json:"status"`
+	Error      string               `json:"error,omitempty"`
+	Duration   string               `json:"duration,omitempty"`
+	// JobID は登録したジョブの ID
+	JobID string `json:"job_id,omitempty"`
+	// Plan はドライランで記録した操作
+	Plan []entity.PlannedAction `json:"plan,omitempty"`
+}
+
+type BackfillReport struct {
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Skipped   int              `json:"skipped"`
+	Planned   int              `json:"planned,omitempty"`
+	Queued    int              `json:"queued,omitempty"`
+	Results   []BackfillResult `json:"results"`
+}
+
+// BackfillUseCase は App のインストール前からあるリポジトリにセットアップを実行する
+type BackfillUseCase struct {
+	githubRepo   repository.GitHubRepository
+	setupUseCase *SetupRepositoryUseCase
+	router       *ProfileRouter
+	newRecorder  func(repository.GitHubRepository) repository.GitHubRecorder
+}
+
+// NewBackfillUseCase は BackfillUseCase を作成する。router が nil の場合は setupUseCase のプロファイルでセットアップする。
+// newRecorder はドライランで変更を記録する GitHubRepository を作る（nil ならドライランは使えない）
+func NewBackfillUseCase(githubRepo repository.GitHubRepository, setupUseCase *SetupRepositoryUseCase, router *ProfileRouter, newRecorder func(repository.GitHubRepository) repository.GitHubRecorder) *BackfillUseCase {
+	return &BackfillUseCase{
+		githubRepo:   githubRepo,
+		setupUseCase: setupUseCase,
+		router:       router,
+		newRecorder:  newRecorder,
+	}
+}
+
+// Run は対象のリポジトリを解決し、Concurrency 件ずつセットアップを実行して結果を返す
+func (uc *BackfillUseCase) Run(ctx context.Context, req BackfillRequest) (*BackfillReport, error) {
+	if err := uc.validate(req); err != nil {
+		return nil, err
+	}
+
+	targets, results, err := uc.resolveTargets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	concurrency := req.Concurrency
+	if concurrency < 1 {
+		concurrency = defaultBackfillConcurrency
+	}
+	slog.InfoContext(ctx, "backfilling repositories", "count", len(targets), "concurrency", concurrency, "dry_run", req.DryRun)
+
+	setupResults := make([]BackfillResult, len(targets))
+	sem := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i, repo := range targets {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				setupResults[i] = BackfillResult{Repository: repo.FullName(), Status: BackfillFailed, Error: ctx.Err().Error()}
+				return
+			}
+			setupResults[i] = uc.setup(ctx, repo, req)
+		}()
+	}
+	wg.Wait()
+
+	report := newBackfillReport(append(results, setupResults...))
+	slog.InfoContext(ctx, "backfill completed", "succeeded", report.Succeeded, "failed", report.Failed, "skipped", report.Skipped, "planned", report.Planned)
+
+	return report, nil
+}
+
+// Enqueue は対象のリポジトリを解決し、セットアップをジョブとして queue に登録する。
+// ジョブは queue のワーカーで実行されるため Concurrency は使わず、ドライランは受け付けない
+func (uc *BackfillUseCase) Enqueue(ctx context.Context, req BackfillRequest, queue *JobQueue) (*BackfillReport, error) {
+	if req.DryRun {
+		return nil, errors.New("dry run cannot be queued")
+	}
+	if err := uc.validate(req); err != nil {
+		return nil, err
+	}
+
+	targets, results, err := uc.resolveTargets(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for _, repo := range targets {
+		result := BackfillResult{Repository: repo.FullName(), Status: BackfillQueued}
+		job, err := queue.EnqueueBackfill(ctx, repo, req.PruneLabels)
+		if err != nil {
+			result.Status = BackfillFailed
+			result.Error = err.Error()
+		} else {
+			result.JobID = job.ID
+		}
+		results = append(results, result)
+	}
+
+	report := newBackfillReport(results)
+	slog.InfoContext(ctx, "backfill queued", "queued", report.Queued, "failed", report.Failed, "skipped", report.Skipped)
+	return report, nil
+}
+
+func (uc *BackfillUseCase) validate(req BackfillRequest) error {
+	if len(req.Repositories) == 0 && req.InstallationID == 0 {
+		return errors.New("repositories or installation_id is required")
+	}
+	if req.DryRun && uc.newRecorder == nil {
+		return errors.New("dry run is not supported")
+	}
+	if req.Filter != "" {
+		if _, err := path.Match(req.Filter, ""); err != nil {
+			return fmt.Errorf("invalid filter %q: %w", req.Filter, err)
+		}
+	}
+	return nil
+}
+
+// newBackfillReport は結果をステータスごとに数える
+func newBackfillReport(results []BackfillResult) *BackfillReport {
+	report := &BackfillReport{Results: results}
+	for _, r := range results {
+		report.Total++
+		switch r.Status {
+		case BackfillSucceeded:
+			report.Succeeded++
+		case BackfillFailed:
+			report.Failed++
+		case BackfillSkipped:
+			report.Skipped++
+		case BackfillPlanned:
+			report.Planned++
+		case BackfillQueued:
+			report.Queued++
+		}
+	}
+	return report
+}
+
+func (uc *BackfillUseCase) setup(ctx context.Context, repo entity.Repository, req BackfillRequest) BackfillResult {
+	ctx = logging.With(ctx, "repository", repo.FullName(), "installation_id", repo.InstallationID)
+	start := time.Now()
+	result := BackfillResult{Repository: repo.FullName(), Status: BackfillSucceeded}
+
+	setupUseCase := uc.setupUseCase
+	if uc.router != nil {
+		profile, decision, err := uc.router.Select(ctx, repo, "")
+		if err != nil {
+			result.Status = BackfillFailed
+			result.Error = err.Error()
+			return result
+		}
+		if profile == nil {
+			result.Status = BackfillSkipped
+			result.Error = "skipped by " + decision.Reason
+			return result
+		}
+		setupUseCase = setupUseCase.WithProfile(profile)
+	}
+	setupUseCase = setupUseCase.WithProfile(backfillProfile(setupUseCase.profile, req.PruneLabels))
+
+	var recorder repository.GitHubRecorder
+	if req.DryRun {
+		recorder = uc.newRecorder(uc.githubRepo)
+		setupUseCase = setupUseCase.withGitHubRepository(recorder)
+	}
+
+	if err := setupUseCase.Execute(ctx, repo); err != nil {
+		result.Status = BackfillFailed
+		result.Error = err.Error()
+	} else if recorder != nil {
+		result.Status = BackfillPlanned
+	}
+	if recorder != nil {
+		result.Plan = recorder.Actions()
+	}
+	result.Duration = time.Since(start).Round(time.Millisecond).String()
+	return result
+}
+
+// backfillProfile は既存リポジトリに適用するプロファイルを返す。
+// prune も profile.BackfillPruneLabels も指定されていない場合は、既存ラベルを削除しないようにする
+func backfillProfile(profile *entity.SetupProfile, prune bool) *entity.SetupProfile {
+	if !profile.PruneLabels || prune || profile.BackfillPruneLabels {
+		return profile
+	}
+	p := *profile
+	p.PruneLabels = false
+	return &p
+}
+
+// resolveTargets はセットアップするリポジトリと、実行前にスキップ・失敗が決まった結果を返す
+func (uc *BackfillUseCase) resolveTargets(ctx context.Context, req BackfillRequest) ([]entity.Repository, []BackfillResult, error) {
+	var targets []entity.Repository
+	var results []BackfillResult
+	seen := make(map[string]bool)
+
+	add := func(repo entity.Repository) {
+		key := strings.ToLower(repo.FullName())
+		if seen[key] {
+			return
+		}
+		seen[key] = true
+
+		switch {
+		case !matchRepository(req.Filter, repo):
+			return
+		case repo.Archived && !req.IncludeArchived:
+			results = append(results, BackfillResult{Repository: repo.FullName(), Status: BackfillSkipped, Error: "archived"})
+		default:
+			targets = append(targets, repo)
+		}
+	}
+
+	if req.InstallationID != 0 {
+		repos, err := uc.githubRepo.ListInstallationRepositories(ctx, req.InstallationID)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, repo := range repos {
+			add(repo)
+		}
+	}
+
+	for _, fullName := range req.Repositories {
+		owner, name, ok := splitFullName(fullName)
+		if !ok {
+			results = append(results, BackfillResult{Repository: fullName, Status: BackfillFailed, Error: "repository must be owner/name"})
+			continue
+		}
+		installationID, err := uc.githubRepo.FindRepositoryInstallation(ctx, owner, name)
+		if err != nil {
+			results = append(results, BackfillResult{Repository: fullName, Status: BackfillFailed, Error: err.Error()})
+			continue
+		}
+		repo := entity.Repository{Owner: owner, Name: name, InstallationID: installationID}
+		// 名前で指定したリポジトリはアーカイブ済みかどうかがわからないため取得する
+		if !req.IncludeArchived {
+			meta, err := uc.githubRepo.GetRepositoryMetadata(ctx, repo)
+			if err != nil {
+				results = append(results, BackfillResult{Repository: fullName, Status: BackfillFailed, Error: err.Error()})
+				continue
+			}
+			repo.Archived = meta.Archived
+		}
+		add(repo)
+	}
+
+	return targets, results, nil
+}
+
+// matchRepository は filter が / を含む場合は owner/name に、含まない場合は name に glob を適用する
+func matchRepository(filter string, repo entity.Repository) bool {
+	if filter == "" {
+		return true
+	}
+	target := repo.Name
+	if strings.Contains(filter, "/") {
+		target = repo.FullName()
+	}
+	ok, _ := path.Match(filter, target)
+	return ok
+}
+
+// splitFullName は owner/name 形式の名前を分割する
+func splitFullName(fullName string) (owner, name string, ok bool) {
+	owner, name, ok = strings.Cut(fullName, "/")
+	if !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+		return "", "", false
+	}
+	return owner, name, true
+}
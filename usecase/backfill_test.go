@@ -0,0 +1,62 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+)
+
+// fakeRepositories はインストールされたリポジトリとそのアーカイブ状態だけを返す GitHubRepository
+type fakeRepositories struct {
+	repository.GitHubRepository
+	archived map[string]bool
+	// metadataCalls は GetRepositoryMetadata の呼び出し回数
+	metadataCalls int
+}
+
+func (f *fakeRepositories) FindRepositoryInstallation(ctx context.Context, owner, name string) (int64, error) {
+	return 1, nil
+}
+
+func (f *fakeRepositories) GetRepositoryMetadata(ctx context.Context, repo entity.Repository) (entity.RepositoryMetadata, error) {
+	f.metadataCalls++
+	return entity.RepositoryMetadata{Archived: f.archived[repo.FullName()]}, nil
+}
+
+func TestBackfillResolveTargetsArchived(t *testing.T) {
+	tests := []struct {
+		name            string
+		includeArchived bool
+		wantTargets     int
+		wantSkipped     int
+		wantCalls       int
+	}{
+		{name: "archived repository is skipped", wantTargets: 1, wantSkipped: 1, wantCalls: 2},
+		{name: "include archived", includeArchived: true, wantTargets: 2, wantCalls: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			github := &fakeRepositories{archived: map[string]bool{"org/old": true}}
+			uc := NewBackfillUseCase(github, nil, nil, nil)
+
+			targets, results, err := uc.resolveTargets(context.Background(), BackfillRequest{
+				Repositories:    []string{"org/app", "org/old"},
+				IncludeArchived: tt.includeArchived,
+			})
+			if err != nil {
+				t.Fatalf("resolveTargets() error = %v", err)
+			}
+			if len(targets) != tt.wantTargets || len(results) != tt.wantSkipped || github.metadataCalls != tt.wantCalls {
+				t.Errorf("targets = %v, results = %v, metadata calls = %d", targets, results, github.metadataCalls)
+			}
+			for _, r := range results {
+				if r.Repository != "org/old" || r.Status != BackfillSkipped || r.Error != "archived" {
+					t.Errorf("result = %+v, want org/old skipped as archived", r)
+				}
+			}
+		})
+	}
+}
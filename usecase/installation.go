@@ -0,0 +1,39 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+
+	"github-setup-app/domain/repository"
+)
+
+// InstallationUseCase は App のインストール単位の状態を管理する
+type InstallationUseCase struct {
+	jobStore repository.JobStore
+	runStore repository.SetupRunStore
+}
+
+func NewInstallationUseCase(jobStore repository.JobStore, runStore repository.SetupRunStore) *InstallationUseCase {
+	return &InstallationUseCase{
+		jobStore: jobStore,
+		runStore: runStore,
+	}
+}
+
+// Purge はアンインストールされた installation のジョブと実行記録を削除する
+func (uc *InstallationUseCase) Purge(ctx context.Context, installationID int64) error {
+	var errs []error
+
+	jobs, err := uc.jobStore.DeleteByInstallation(ctx, installationID)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	runs, err := uc.runStore.DeleteByInstallation(ctx, installationID)
+	if err != nil {
+		errs = append(errs, err)
+	}
+
+	slog.InfoContext(ctx, "purged installation", "installation_id", installationID, "jobs", jobs, "setup_runs", runs)
+	return errors.Join(errs...)
+}
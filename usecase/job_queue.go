@@ -0,0 +1,363 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+	"github-setup-app/logging"
+	"github-setup-app/tracing"
+)
+
+const (
+	// defaultMaxJobAttempts を超えて失敗したジョブは failed のまま残す
+	defaultMaxJobAttempts = 5
+	jobPollInterval       = 5 * time.Second
+	jobRetryBaseDelay     = 30 * time.Second
+	jobRetryMaxDelay      = 10 * time.Minute
+	// jobSweepInterval ごとに保持期間を過ぎた failed のジョブを削除する
+	jobSweepInterval = time.Hour
+)
+
+// JobQueue は Webhook から受け取った処理を JobStore に保存し、ワーカーで実行する。
+// ジョブは成功するまでストアから削除しないため、再起動をまたいで少なくとも1回は実行される。
+type JobQueue struct {
+	store        repository.JobStore
+	setupUseCase *SetupRepositoryUseCase
+	router       *ProfileRouter
+	workers      int
+	maxAttempts  int
+	// failedRetention を過ぎた failed のジョブはストアから削除する
+	failedRetention time.Duration
+	wake            chan struct{}
+	wg              sync.WaitGroup
+	// alive は実行中のワーカー数、stopping は Shutdown が呼ばれたかどうか
+	alive    atomic.Int32
+	stopping atomic.Bool
+
+	// stopClaiming は新しいジョブの取得を止め、cancelRunning は実行中のジョブを中断する
+	stopClaiming  context.CancelFunc
+	cancelRunning context.CancelFunc
+}
+
+// NewJobQueue は JobQueue を作成する。router が nil の場合は setupUseCase のプロファイルでセットアップする。
+// 再試行を使い切った failed のジョブは failedRetention の間だけ残す
+func NewJobQueue(store repository.JobStore, setupUseCase *SetupRepositoryUseCase, router *ProfileRouter, workers int, failedRetention time.Duration) *JobQueue {
+	if workers < 1 {
+		workers = 1
+	}
+	return &JobQueue{
+		store:        store,
+		setupUseCase: setupUseCase,
+		router:       router,
+		workers:      workers,
+		maxAttempts:  defaultMaxJobAttempts,
+		wake:         make(chan struct{}, workers),
+
+		failedRetention: failedRetention,
+	}
+}
+
+// EnqueueSetup は profile でセットアップするジョブを登録する。profile が空なら実行時にルールで選ぶ
+func (q *JobQueue) EnqueueSetup(ctx context.Context, repo entity.Repository, profile string) (*entity.Job, error) {
+	return q.enqueue(ctx, &entity.Job{Type: entity.JobTypeSetupRepository, Repository: repo, Profile: profile})
+}
+
+// EnqueueBackfill は既存リポジトリをセットアップするジョブを登録する。プロファイルは実行時にルールで選ぶ
+func (q *JobQueue) EnqueueBackfill(ctx context.Context, repo entity.Repository, pruneLabels bool) (*entity.Job, error) {
+	return q.enqueue(ctx, &entity.Job{Type: entity.JobTypeSetupRepository, Repository: repo, Backfill: true, PruneLabels: pruneLabels})
+}
+
+func (q *JobQueue) EnqueueDeleteWorkflow(ctx context.Context, repo entity.Repository) (*entity.Job, error) {
+	return q.enqueue(ctx, &entity.Job{Type: entity.JobTypeDeleteWorkflow, Repository: repo})
+}
+
+// enqueue は job に ID や時刻を設定して登録する
+func (q *JobQueue) enqueue(ctx context.Context, job *entity.Job) (*entity.Job, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	job.ID = id
+	job.DeliveryID = deliveryID(ctx)
+	job.TraceParent = tracing.Inject(ctx)
+	job.Status = entity.JobStatusPending
+	job.CreatedAt = now
+	job.UpdatedAt = now
+	job.RunAfter = now
+
+	if err := q.store.Enqueue(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to enqueue job: %w", err)
+	}
+	slog.InfoContext(ctx, "enqueued job", "job_id", job.ID, "job_type", job.Type, "repository", job.Repository.FullName())
+
+	// 待機中のワーカーを起こす
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+// Depth はステータスごとのジョブ数を返す（ジョブがないステータスは 0）
+func (q *JobQueue) Depth(ctx context.Context) (map[entity.JobStatus]int, error) {
+	counts, err := q.store.CountByStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range []entity.JobStatus{entity.JobStatusPending, entity.JobStatusRunning, entity.JobStatusFailed} {
+		if _, ok := counts[status]; !ok {
+			counts[status] = 0
+		}
+	}
+	return counts, nil
+}
+
+// CheckWorkers はワーカーが全て動いているかを確認する
+func (q *JobQueue) CheckWorkers() error {
+	switch alive := int(q.alive.Load()); {
+	case q.stopClaiming == nil:
+		return errors.New("job workers are not started")
+	case q.stopping.Load():
+		return errors.New("job queue is shutting down")
+	case alive < q.workers:
+		return fmt.Errorf("%d of %d job workers are running", alive, q.workers)
+	}
+	return nil
+}
+
+// Start は前回 running のまま終了したジョブを戻してからワーカーを起動する。
+// ワーカーは Shutdown を呼ぶか ctx がキャンセルされると終了する。
+func (q *JobQueue) Start(ctx context.Context) error {
+	n, err := q.store.ResetRunning(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to reset running jobs: %w", err)
+	}
+	if n > 0 {
+		slog.InfoContext(ctx, "requeued interrupted jobs", "count", n)
+	}
+
+	claimCtx, stopClaiming := context.WithCancel(ctx)
+	runCtx, cancelRunning := context.WithCancel(ctx)
+	q.stopClaiming = stopClaiming
+	q.cancelRunning = cancelRunning
+
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		q.alive.Add(1)
+		go func() {
+			defer q.wg.Done()
+			defer q.alive.Add(-1)
+			q.work(claimCtx, runCtx)
+		}()
+	}
+	q.wg.Add(1)
+	go func() {
+		defer q.wg.Done()
+		q.sweep(claimCtx)
+	}()
+	slog.InfoContext(ctx, "started job workers", "workers", q.workers)
+	return nil
+}
+
+// sweep は起動時と jobSweepInterval ごとに、保持期間を過ぎた failed のジョブを削除する
+func (q *JobQueue) sweep(ctx context.Context) {
+	ticker := time.NewTicker(jobSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		n, err := q.store.DeleteFailed(ctx, time.Now().Add(-q.failedRetention))
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to delete expired failed jobs", "error", err)
+		} else if n > 0 {
+			slog.InfoContext(ctx, "deleted expired failed jobs", "count", n, "retention", q.failedRetention)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Shutdown は新しいジョブの取得を止め、実行中のジョブの完了を待つ。
+// ctx の期限を過ぎた場合は実行中のジョブを中断し、次回起動時に再実行されるよう pending に戻す。
+func (q *JobQueue) Shutdown(ctx context.Context) error {
+	if q.stopClaiming == nil {
+		return nil
+	}
+	q.stopping.Store(true)
+	q.stopClaiming()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		q.cancelRunning()
+		slog.InfoContext(ctx, "all job workers stopped")
+		return nil
+	case <-ctx.Done():
+		slog.WarnContext(ctx, "shutdown deadline exceeded, cancelling running jobs")
+		q.cancelRunning()
+		<-done
+		return ctx.Err()
+	}
+}
+
+func (q *JobQueue) work(claimCtx, runCtx context.Context) {
+	ticker := time.NewTicker(jobPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if claimCtx.Err() != nil {
+			return
+		}
+
+		job, err := q.store.Claim(claimCtx, time.Now())
+		if err != nil {
+			slog.ErrorContext(claimCtx, "failed to claim job", "error", err)
+		}
+		if job != nil {
+			q.run(runCtx, job)
+			continue
+		}
+
+		select {
+		case <-claimCtx.Done():
+			return
+		case <-q.wake:
+		case <-ticker.C:
+		}
+	}
+}
+
+func (q *JobQueue) run(ctx context.Context, job *entity.Job) {
+	repo := job.Repository
+	// Webhook の配信 ID でジョブのログを Webhook の受信ログと関連付ける
+	ctx = logging.With(ctx,
+		"job_id", job.ID,
+		"job_type", job.Type,
+		"delivery_id", job.DeliveryID,
+		"repository", repo.FullName(),
+		"installation_id", repo.InstallationID,
+	)
+
+	// 試行回数は Claim で数えるため、実行中にプロセスが終了し続けたジョブもここで打ち切る
+	if job.Attempts > q.maxAttempts {
+		job.Status = entity.JobStatusFailed
+		job.LastError = fmt.Sprintf("job did not finish within %d attempts", q.maxAttempts)
+		job.UpdatedAt = time.Now()
+		slog.ErrorContext(ctx, "job failed permanently", "attempts", q.maxAttempts, "error", job.LastError)
+		if err := q.store.Save(context.WithoutCancel(ctx), job); err != nil {
+			slog.ErrorContext(ctx, "failed to save job", "error", err)
+		}
+		return
+	}
+	slog.InfoContext(ctx, "running job", "attempt", job.Attempts)
+
+	// ジョブは Webhook の受信とは別のトレースにし、登録した Webhook のスパンにリンクする
+	ctx, span := tracing.Start(ctx, "JobQueue.run",
+		tracing.Link(job.TraceParent),
+		trace.WithNewRoot(),
+		trace.WithAttributes(
+			attribute.String("job.id", job.ID),
+			attribute.String("job.type", string(job.Type)),
+			attribute.Int("job.attempt", job.Attempts),
+			attribute.String("github.repository", repo.FullName()),
+		),
+	)
+
+	var err error
+	defer func() { tracing.End(span, err) }()
+	switch job.Type {
+	case entity.JobTypeSetupRepository:
+		err = q.runSetup(ctx, job)
+	case entity.JobTypeDeleteWorkflow:
+		err = q.setupUseCase.DeleteWorkflow(ctx, repo)
+	default:
+		err = fmt.Errorf("unknown job type %q", job.Type)
+	}
+
+	// 中断された場合もジョブの状態は保存する
+	storeCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		if err := q.store.Delete(storeCtx, job.ID); err != nil {
+			slog.ErrorContext(ctx, "failed to delete completed job", "error", err)
+		}
+		slog.InfoContext(ctx, "job completed")
+		return
+	}
+
+	now := time.Now()
+	job.LastError = err.Error()
+	job.UpdatedAt = now
+	if ctx.Err() != nil {
+		// シャットダウンによる中断は失敗として数えない
+		job.Attempts--
+		job.Status = entity.JobStatusPending
+		job.RunAfter = now
+		slog.WarnContext(ctx, "job interrupted, will resume on next start")
+	} else if job.Attempts >= q.maxAttempts {
+		job.Status = entity.JobStatusFailed
+		slog.ErrorContext(ctx, "job failed permanently", "attempts", job.Attempts, "error", err)
+	} else {
+		job.Status = entity.JobStatusPending
+		job.RunAfter = now.Add(retryDelay(job.Attempts))
+		slog.WarnContext(ctx, "job failed, will retry", "attempt", job.Attempts, "retry_at", job.RunAfter, "error", err)
+	}
+	if err := q.store.Save(storeCtx, job); err != nil {
+		slog.ErrorContext(ctx, "failed to save job", "error", err)
+	}
+}
+
+// runSetup はジョブのプロファイル（未指定ならルールで選んだプロファイル）でセットアップする
+func (q *JobQueue) runSetup(ctx context.Context, job *entity.Job) error {
+	setupUseCase := q.setupUseCase
+	if q.router != nil {
+		// profile が nil の場合はルールでスキップされた
+		profile, _, err := q.router.Select(ctx, job.Repository, job.Profile)
+		if err != nil || profile == nil {
+			return err
+		}
+		setupUseCase = setupUseCase.WithProfile(profile)
+	}
+	if job.Backfill {
+		setupUseCase = setupUseCase.WithProfile(backfillProfile(setupUseCase.profile, job.PruneLabels))
+	}
+	return setupUseCase.Execute(ctx, job.Repository)
+}
+
+// deliveryID は Webhook ハンドラーが ctx に付けた配信 ID を返す
+func deliveryID(ctx context.Context) string {
+	if v, ok := logging.Attr(ctx, "delivery_id"); ok {
+		return v.String()
+	}
+	return ""
+}
+
+// retryDelay は attempts 回目の失敗後の待ち時間（指数バックオフ）
+func retryDelay(attempts int) time.Duration {
+	delay := jobRetryBaseDelay
+	for i := 1; i < attempts && delay < jobRetryMaxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, jobRetryMaxDelay)
+}
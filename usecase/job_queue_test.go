@@ -0,0 +1,254 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+	"github-setup-app/infrastructure/store"
+)
+
+// fakeGitHub は DeleteWorkflowFile だけを差し替えた GitHubRepository
+type fakeGitHub struct {
+	repository.GitHubRepository
+	deleteWorkflowFile func(ctx context.Context) error
+	calls              atomic.Int32
+}
+
+func (f *fakeGitHub) DeleteWorkflowFile(ctx context.Context, repo entity.Repository, path string) error {
+	f.calls.Add(1)
+	return f.deleteWorkflowFile(ctx)
+}
+
+func newTestQueue(jobStore repository.JobStore, deleteWorkflowFile func(ctx context.Context) error) (*JobQueue, *fakeGitHub) {
+	github := &fakeGitHub{deleteWorkflowFile: deleteWorkflowFile}
+	setup := NewSetupRepositoryUseCase(github, nil, "1", nil, nil)
+	return NewJobQueue(jobStore, setup, nil, 1, time.Hour), github
+}
+
+func deleteWorkflowJob(attempts int, status entity.JobStatus) *entity.Job {
+	now := time.Now()
+	return &entity.Job{
+		ID:         "job",
+		Type:       entity.JobTypeDeleteWorkflow,
+		Repository: entity.Repository{Owner: "org", Name: "app", InstallationID: 1},
+		Status:     status,
+		Attempts:   attempts,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+		RunAfter:   now,
+	}
+}
+
+// waitFor は cond が true になるまで待つ
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func jobCount(jobStore repository.JobStore, status entity.JobStatus) int {
+	counts, _ := jobStore.CountByStatus(context.Background())
+	return counts[status]
+}
+
+func TestJobQueueRun(t *testing.T) {
+	errFailed := errors.New("boom")
+
+	tests := []struct {
+		name string
+		// attempts は Claim する前の試行回数
+		attempts  int
+		err       error
+		cancelled bool
+		// wantStatus が空ならジョブは削除される
+		wantStatus   entity.JobStatus
+		wantAttempts int
+		wantDelay    time.Duration
+		wantCalls    int32
+	}{
+		{name: "success", wantCalls: 1},
+		{name: "retry after failure", err: errFailed, wantStatus: entity.JobStatusPending, wantAttempts: 1, wantDelay: jobRetryBaseDelay, wantCalls: 1},
+		{name: "backoff grows", attempts: 2, err: errFailed, wantStatus: entity.JobStatusPending, wantAttempts: 3, wantDelay: 4 * jobRetryBaseDelay, wantCalls: 1},
+		{name: "last attempt fails", attempts: defaultMaxJobAttempts - 1, err: errFailed, wantStatus: entity.JobStatusFailed, wantAttempts: defaultMaxJobAttempts, wantCalls: 1},
+		{name: "crashed on every attempt", attempts: defaultMaxJobAttempts, wantStatus: entity.JobStatusFailed, wantAttempts: defaultMaxJobAttempts + 1, wantCalls: 0},
+		{name: "interrupted by shutdown", attempts: 1, cancelled: true, wantStatus: entity.JobStatusPending, wantAttempts: 1, wantCalls: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			jobStore := store.NewMemoryJobStore()
+			q, github := newTestQueue(jobStore, func(ctx context.Context) error {
+				if tt.cancelled {
+					cancel()
+					return ctx.Err()
+				}
+				return tt.err
+			})
+			jobStore.Enqueue(ctx, deleteWorkflowJob(tt.attempts, entity.JobStatusPending))
+
+			job, err := jobStore.Claim(ctx, time.Now())
+			if err != nil || job == nil {
+				t.Fatalf("Claim() = %v, %v", job, err)
+			}
+			started := time.Now()
+			q.run(ctx, job)
+
+			if got := github.calls.Load(); got != tt.wantCalls {
+				t.Errorf("DeleteWorkflowFile called %d times, want %d", got, tt.wantCalls)
+			}
+			if tt.wantStatus == "" {
+				if counts, _ := jobStore.CountByStatus(ctx); len(counts) != 0 {
+					t.Errorf("jobs = %v, want the job to be deleted", counts)
+				}
+				return
+			}
+
+			// 保存されたジョブを取り出して確かめる
+			jobStore.ResetRunning(ctx)
+			if jobCount(jobStore, tt.wantStatus) != 1 {
+				t.Fatalf("job status is not %s", tt.wantStatus)
+			}
+			if tt.wantStatus == entity.JobStatusFailed {
+				return
+			}
+			saved, _ := jobStore.Claim(ctx, started.Add(tt.wantDelay).Add(time.Second))
+			if saved == nil {
+				t.Fatalf("job is not runnable after %v", tt.wantDelay)
+			}
+			if saved.Attempts-1 != tt.wantAttempts {
+				t.Errorf("Attempts = %d, want %d", saved.Attempts-1, tt.wantAttempts)
+			}
+			if tt.wantDelay > 0 && !saved.RunAfter.After(started.Add(tt.wantDelay-time.Second)) {
+				t.Errorf("RunAfter = %v, want about %v later", saved.RunAfter, tt.wantDelay)
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 30 * time.Second},
+		{2, time.Minute},
+		{3, 2 * time.Minute},
+		{5, 8 * time.Minute},
+		{6, jobRetryMaxDelay},
+		{100, jobRetryMaxDelay},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestJobQueueStartResetsRunning(t *testing.T) {
+	tests := []struct {
+		name string
+		// attempts は前回の実行中に終了したときの試行回数
+		attempts   int
+		wantCalls  int32
+		wantFailed int
+	}{
+		{name: "resumed", attempts: 1, wantCalls: 1},
+		{name: "gives up after repeated crashes", attempts: defaultMaxJobAttempts, wantFailed: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobStore := store.NewMemoryJobStore()
+			jobStore.Enqueue(ctx, deleteWorkflowJob(tt.attempts, entity.JobStatusRunning))
+			q, github := newTestQueue(jobStore, func(context.Context) error { return nil })
+
+			if err := q.Start(ctx); err != nil {
+				t.Fatalf("Start() error = %v", err)
+			}
+			waitFor(t, func() bool {
+				counts, _ := jobStore.CountByStatus(ctx)
+				return counts[entity.JobStatusPending]+counts[entity.JobStatusRunning] == 0
+			})
+			if err := q.Shutdown(ctx); err != nil {
+				t.Fatalf("Shutdown() error = %v", err)
+			}
+
+			if got := github.calls.Load(); got != tt.wantCalls {
+				t.Errorf("DeleteWorkflowFile called %d times, want %d", got, tt.wantCalls)
+			}
+			if got := jobCount(jobStore, entity.JobStatusFailed); got != tt.wantFailed {
+				t.Errorf("failed jobs = %d, want %d", got, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestJobQueueShutdown(t *testing.T) {
+	tests := []struct {
+		name string
+		// timeout は Shutdown の期限。ジョブはそれより長くかかる場合は中断される
+		timeout     time.Duration
+		wantErr     error
+		wantPending int
+	}{
+		{name: "drains running jobs", timeout: 5 * time.Second},
+		{name: "cancels jobs after the deadline", timeout: 50 * time.Millisecond, wantErr: context.DeadlineExceeded, wantPending: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			jobStore := store.NewMemoryJobStore()
+			started := make(chan struct{})
+			q, _ := newTestQueue(jobStore, func(ctx context.Context) error {
+				close(started)
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-time.After(500 * time.Millisecond):
+					return nil
+				}
+			})
+			if err := q.Start(ctx); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := q.EnqueueDeleteWorkflow(ctx, entity.Repository{Owner: "org", Name: "app", InstallationID: 1}); err != nil {
+				t.Fatal(err)
+			}
+			<-started
+
+			shutdownCtx, cancel := context.WithTimeout(ctx, tt.timeout)
+			defer cancel()
+			if err := q.Shutdown(shutdownCtx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Shutdown() error = %v, want %v", err, tt.wantErr)
+			}
+			if err := q.CheckWorkers(); err == nil {
+				t.Error("CheckWorkers() error = nil after shutdown")
+			}
+
+			if got := jobCount(jobStore, entity.JobStatusPending); got != tt.wantPending {
+				t.Errorf("pending jobs = %d, want %d", got, tt.wantPending)
+			}
+			// 中断したジョブは試行として数えない
+			if tt.wantPending > 0 {
+				job, _ := jobStore.Claim(ctx, time.Now())
+				if job == nil || job.Attempts != 1 {
+					t.Errorf("interrupted job = %+v, want it claimable as its first attempt", job)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,75 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+)
+
+// LabelUseCase は CLI からリポジトリのラベルを操作する
+type LabelUseCase struct {
+	githubRepo   repository.GitHubRepository
+	setupUseCase *SetupRepositoryUseCase
+	router       *ProfileRouter
+}
+
+func NewLabelUseCase(githubRepo repository.GitHubRepository, setupUseCase *SetupRepositoryUseCase, router *ProfileRouter) *LabelUseCase {
+	return &LabelUseCase{
+		githubRepo:   githubRepo,
+		setupUseCase: setupUseCase,
+		router:       router,
+	}
+}
+
+// Sync は owner/name のラベルを profile（空ならルールで選んだプロファイル）のラベルに揃え、実行した操作を返す。
+// dryRun の場合は操作を計算するだけで変更しない
+func (uc *LabelUseCase) Sync(ctx context.Context, fullName, profile string, dryRun bool) (entity.LabelPlan, error) {
+	repo, err := uc.findRepository(ctx, fullName)
+	if err != nil {
+		return entity.LabelPlan{}, err
+	}
+
+	setupUseCase := uc.setupUseCase
+	if uc.router != nil {
+		selected, decision, err := uc.router.Select(ctx, repo, profile)
+		if err != nil {
+			return entity.LabelPlan{}, err
+		}
+		if selected == nil {
+			return entity.LabelPlan{}, fmt.Errorf("%s is skipped by %s, specify a profile to sync labels", fullName, decision.Reason)
+		}
+		setupUseCase = setupUseCase.WithProfile(selected)
+	}
+
+	plan, err := setupUseCase.PlanLabels(ctx, repo)
+	if err != nil {
+		return entity.LabelPlan{}, err
+	}
+	if dryRun || plan.IsEmpty() {
+		return plan, nil
+	}
+	return plan, setupUseCase.ApplyLabelPlan(ctx, repo, plan)
+}
+
+// Export は owner/name の現在のラベルを返す
+func (uc *LabelUseCase) Export(ctx context.Context, fullName string) ([]entity.Label, error) {
+	repo, err := uc.findRepository(ctx, fullName)
+	if err != nil {
+		return nil, err
+	}
+	return uc.githubRepo.ListLabels(ctx, repo)
+}
+
+func (uc *LabelUseCase) findRepository(ctx context.Context, fullName string) (entity.Repository, error) {
+	owner, name, ok := splitFullName(fullName)
+	if !ok {
+		return entity.Repository{}, fmt.Errorf("repository must be owner/name: %q", fullName)
+	}
+	installationID, err := uc.githubRepo.FindRepositoryInstallation(ctx, owner, name)
+	if err != nil {
+		return entity.Repository{}, err
+	}
+	return entity.Repository{Owner: owner, Name: name, InstallationID: installationID}, nil
+}
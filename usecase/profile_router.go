@@ -0,0 +1,76 @@
+package usecase
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+)
+
+// ProfileRouter はリポジトリの属性とルールから適用するプロファイルを選ぶ
+type ProfileRouter struct {
+	githubRepo repository.GitHubRepository
+	profiles   map[string]*entity.SetupProfile
+	rules      entity.SetupRules
+}
+
+// NewProfileRouter は ProfileRouter を作成する。profiles には rules が参照する全てのプロファイルを含める
+func NewProfileRouter(githubRepo repository.GitHubRepository, profiles map[string]*entity.SetupProfile, rules entity.SetupRules) *ProfileRouter {
+	return &ProfileRouter{
+		githubRepo: githubRepo,
+		profiles:   profiles,
+		rules:      rules,
+	}
+}
+
+// Decide は Webhook のペイロードなどから得た属性でプロファイルを選ぶ
+func (r *ProfileRouter) Decide(ctx context.Context, repo entity.Repository, meta entity.RepositoryMetadata) entity.SetupDecision {
+	decision := r.rules.Decide(repo, meta)
+	if decision.Skip {
+		slog.InfoContext(ctx, "setup skipped by rule", "repository", repo.FullName(), "reason", decision.Reason)
+	} else {
+		slog.InfoContext(ctx, "selected setup profile", "repository", repo.FullName(), "profile", decision.Profile, "reason", decision.Reason)
+	}
+	return decision
+}
+
+// Resolve は GitHub API からリポジトリの属性を取得してプロファイルを選ぶ
+func (r *ProfileRouter) Resolve(ctx context.Context, repo entity.Repository) (entity.SetupDecision, error) {
+	meta, err := r.githubRepo.GetRepositoryMetadata(ctx, repo)
+	if err != nil {
+		return entity.SetupDecision{}, err
+	}
+	return r.Decide(ctx, repo, meta), nil
+}
+
+// Profile は名前でプロファイルを返す
+func (r *ProfileRouter) Profile(name string) (*entity.SetupProfile, error) {
+	profile, ok := r.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown profile %q", name)
+	}
+	return profile, nil
+}
+
+// Select は name のプロファイルを返す。name が空の場合はルールで選び、スキップする場合は nil を返す
+func (r *ProfileRouter) Select(ctx context.Context, repo entity.Repository, name string) (*entity.SetupProfile, entity.SetupDecision, error) {
+	decision := entity.SetupDecision{Profile: name, Reason: "specified"}
+	if name == "" {
+		var err error
+		decision, err = r.Resolve(ctx, repo)
+		if err != nil {
+			return nil, decision, err
+		}
+		if decision.Skip {
+			return nil, decision, nil
+		}
+	}
+
+	profile, err := r.Profile(decision.Profile)
+	if err != nil {
+		return nil, decision, err
+	}
+	return profile, decision, nil
+}
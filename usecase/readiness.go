@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// readinessCheckTimeout を超えた確認は失敗とみなす
+const readinessCheckTimeout = 5 * time.Second
+
+// ReadinessCheck は /ready で確認する項目
+type ReadinessCheck struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// ReadinessResult は1つの確認の結果
+type ReadinessResult struct {
+	Name     string `json:"name"`
+	OK       bool   `json:"ok"`
+	Error    string `json:"error,omitempty"`
+	Duration string `json:"duration"`
+}
+
+// ReadinessReport は全ての確認の結果。全て成功した場合のみ Ready
+type ReadinessReport struct {
+	Ready  bool              `json:"ready"`
+	Checks []ReadinessResult `json:"checks"`
+}
+
+// ReadinessUseCase はリクエストを処理できる状態かを確認する
+type ReadinessUseCase struct {
+	checks []ReadinessCheck
+}
+
+func NewReadinessUseCase(checks ...ReadinessCheck) *ReadinessUseCase {
+	return &ReadinessUseCase{checks: checks}
+}
+
+// Check は全ての項目を並行に確認する。結果は登録した順に並べる
+func (uc *ReadinessUseCase) Check(ctx context.Context) ReadinessReport {
+	ctx, cancel := context.WithTimeout(ctx, readinessCheckTimeout)
+	defer cancel()
+
+	results := make([]ReadinessResult, len(uc.checks))
+	var wg sync.WaitGroup
+	for i, c := range uc.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := c.Check(ctx)
+			results[i] = ReadinessResult{
+				Name:     c.Name,
+				OK:       err == nil,
+				Duration: time.Since(start).Round(time.Microsecond).String(),
+			}
+			if err != nil {
+				results[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := ReadinessReport{Ready: true, Checks: results}
+	for _, r := range results {
+		if !r.OK {
+			report.Ready = false
+		}
+	}
+	return report
+}
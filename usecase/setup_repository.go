@@ -2,90 +2,380 @@ package usecase
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 
 	"github-setup-app/domain/entity"
 	"github-setup-app/domain/repository"
+	"github-setup-app/logging"
+	"github-setup-app/metrics"
+	"github-setup-app/tracing"
 )
 
 type SetupRepositoryUseCase struct {
-	githubRepo       repository.GitHubRepository
-	appID            string
-	appPrivateKey    string
+	githubRepo    repository.GitHubRepository
+	runStore      repository.SetupRunStore
+	appID         string
+	appPrivateKey repository.PrivateKeyProvider
+	profile       *entity.SetupProfile
 }
 
-func NewSetupRepositoryUseCase(githubRepo repository.GitHubRepository, appID, appPrivateKey string) *SetupRepositoryUseCase {
+// NewSetupRepositoryUseCase は SetupRepositoryUseCase を作成する。runStore が nil の場合は実行記録を保存しない
+func NewSetupRepositoryUseCase(githubRepo repository.GitHubRepository, runStore repository.SetupRunStore, appID string, appPrivateKey repository.PrivateKeyProvider, profile *entity.SetupProfile) *SetupRepositoryUseCase {
+	if profile == nil {
+		profile = entity.DefaultSetupProfile()
+	}
 	return &SetupRepositoryUseCase{
 		githubRepo:    githubRepo,
+		runStore:      runStore,
 		appID:         appID,
 		appPrivateKey: appPrivateKey,
+		profile:       profile,
 	}
 }
 
-func (uc *SetupRepositoryUseCase) Execute(ctx context.Context, repo entity.Repository) error {
-	log.Printf("Setting up repository: %s/%s", repo.Owner, repo.Name)
+// WithProfile は profile を適用する SetupRepositoryUseCase を返す
+func (uc *SetupRepositoryUseCase) WithProfile(profile *entity.SetupProfile) *SetupRepositoryUseCase {
+	c := *uc
+	c.profile = profile
+	return &c
+}
+
+// withGitHubRepository は githubRepo で GitHub にアクセスする SetupRepositoryUseCase を返す。
+// ドライランで使うため、実行記録は保存しない
+func (uc *SetupRepositoryUseCase) withGitHubRepository(githubRepo repository.GitHubRepository) *SetupRepositoryUseCase {
+	c := *uc
+	c.githubRepo = githubRepo
+	c.runStore = nil
+	return &c
+}
+
+func (uc *SetupRepositoryUseCase) Execute(ctx context.Context, repo entity.Repository) (err error) {
+	ctx, span := tracing.Start(ctx, "SetupRepositoryUseCase.Execute", trace.WithAttributes(
+		attribute.String("github.repository", repo.FullName()),
+		attribute.String("setup.profile", uc.profile.Name),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	ctx = logging.With(ctx, "repository", repo.FullName(), "profile", uc.profile.Name)
+	slog.InfoContext(ctx, "setting up repository")
+
+	id, err := newID()
+	if err != nil {
+		return err
+	}
+	run := entity.NewSetupRun(id, repo, uc.profile.Name, time.Now())
+	uc.saveRun(ctx, run)
 
 	// シークレットを登録
-	if err := uc.createSecrets(ctx, repo); err != nil {
-		log.Printf("Error creating secrets: %v", err)
+	if err := uc.runStep(ctx, run, entity.SetupStepSecrets, func(ctx context.Context) error {
+		return uc.createSecrets(ctx, repo)
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to create secrets", "error", err)
 		return err
 	}
 
 	// テンプレートファイルを一括作成
-	if err := uc.createTemplateFiles(ctx, repo); err != nil {
-		log.Printf("Error creating template files: %v", err)
+	if err := uc.runStep(ctx, run, entity.SetupStepFiles, func(ctx context.Context) error {
+		return uc.createTemplateFiles(ctx, repo)
+	}); err != nil {
+		slog.ErrorContext(ctx, "failed to create template files", "error", err)
 		return err
 	}
 
-	log.Printf("Repository setup completed: %s/%s", repo.Owner, repo.Name)
+	// ラベルを同期（ワークフローモードではワークフロー側で作成し、完了後に削除する）
+	if uc.profile.Workflows.SetupLabels {
+		run.SetStep(entity.SetupStepLabels, entity.StepStatusWaiting, time.Now())
+		run.SetStep(entity.SetupStepCleanup, entity.StepStatusWaiting, time.Now())
+	} else {
+		if err := uc.runStep(ctx, run, entity.SetupStepLabels, func(ctx context.Context) error {
+			return uc.SyncLabels(ctx, repo)
+		}); err != nil {
+			slog.ErrorContext(ctx, "failed to sync labels", "error", err)
+			return err
+		}
+		run.SetStep(entity.SetupStepCleanup, entity.StepStatusSkipped, time.Now())
+	}
+
+	run.Finish(time.Now())
+	uc.saveRun(ctx, run)
+	uc.observeRun(run)
+
+	slog.InfoContext(ctx, "repository setup completed", "status", run.Status)
 	return nil
 }
 
+// runStep はステップの開始と終了を実行記録に残しながら fn を実行する
+func (uc *SetupRepositoryUseCase) runStep(ctx context.Context, run *entity.SetupRun, step entity.SetupStep, fn func(ctx context.Context) error) error {
+	ctx, span := tracing.Start(ctx, "SetupRepositoryUseCase."+string(step))
+	ctx = logging.With(ctx, "step", step)
+	run.StartStep(step, time.Now())
+	uc.saveRun(ctx, run)
+
+	err := fn(ctx)
+	tracing.End(span, err)
+	run.FinishStep(step, err, time.Now())
+	uc.saveRun(ctx, run)
+	if err != nil {
+		uc.observeRun(run)
+	}
+	return err
+}
+
+// observeRun は終了した実行の結果をメトリクスに記録する。ドライランは記録しない。
+// ワークフロー待ち（waiting）はワークフローの完了時に DeleteWorkflow で数えるため、ここでは数えない
+func (uc *SetupRepositoryUseCase) observeRun(run *entity.SetupRun) {
+	if uc.runStore == nil || run.Status == entity.SetupRunWaiting {
+		return
+	}
+	metrics.ObserveSetupRun(string(run.Status), string(run.FailedStep()))
+}
+
+// saveRun は実行記録を保存する。保存に失敗してもセットアップは続ける
+func (uc *SetupRepositoryUseCase) saveRun(ctx context.Context, run *entity.SetupRun) {
+	if uc.runStore == nil {
+		return
+	}
+	if err := uc.runStore.Save(context.WithoutCancel(ctx), run); err != nil {
+		slog.ErrorContext(ctx, "failed to save setup run", "run_id", run.ID, "error", err)
+	}
+}
+
 func (uc *SetupRepositoryUseCase) createSecrets(ctx context.Context, repo entity.Repository) error {
-	log.Printf("Creating secrets for repository: %s/%s", repo.Owner, repo.Name)
+	slog.InfoContext(ctx, "creating secrets")
+
+	for _, secret := range uc.profile.Secrets {
+		value, err := uc.secretValue(secret)
+		if err != nil {
+			return err
+		}
+		if err := uc.githubRepo.CreateSecret(ctx, repo, secret.Name, value); err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "created secret", "secret", secret.Name)
+	}
 
-	// APP_ID を登録
-	if err := uc.githubRepo.CreateSecret(ctx, repo, "APP_ID", uc.appID); err != nil {
+	return nil
+}
+
+// secretValue はプロファイルのシークレット定義から登録する値を決める
+func (uc *SetupRepositoryUseCase) secretValue(secret entity.Secret) (string, error) {
+	switch secret.Source {
+	case entity.SecretSourceLabelAppID:
+		return uc.appID, nil
+	case entity.SecretSourceLabelAppPrivateKey:
+		// 鍵を入れ替えた後は新しい鍵を登録する
+		return string(uc.appPrivateKey.PrivateKey()), nil
+	case entity.SecretSourceValue:
+		return secret.Value, nil
+	default:
+		return "", fmt.Errorf("unknown source %q for secret %s", secret.Source, secret.Name)
+	}
+}
+
+func (uc *SetupRepositoryUseCase) createTemplateFiles(ctx context.Context, repo entity.Repository) error {
+	slog.InfoContext(ctx, "creating template files")
+
+	meta, err := uc.githubRepo.GetRepositoryMetadata(ctx, repo)
+	if err != nil {
+		return err
+	}
+	sourced, err := uc.loadTemplateSource(ctx, repo)
+	if err != nil {
+		return err
+	}
+	templateFiles, err := uc.profile.TemplateFiles(entity.NewTemplateData(repo, meta, time.Now().Year()), sourced)
+	if err != nil {
 		return err
 	}
-	log.Printf("Created APP_ID secret")
 
-	// APP_PRIVATE_KEY を登録
-	if err := uc.githubRepo.CreateSecret(ctx, repo, "APP_PRIVATE_KEY", uc.appPrivateKey); err != nil {
+	// ワークフローファイルは最後にpushされる
+	files, err := uc.resolveConflicts(ctx, repo, templateFiles)
+	if err != nil {
 		return err
 	}
-	log.Printf("Created APP_PRIVATE_KEY secret")
+	if len(files) == 0 {
+		slog.InfoContext(ctx, "no template files to create")
+		return nil
+	}
 
+	// 全ファイルを1コミットで作成
+	if err := uc.githubRepo.CreateFiles(ctx, repo, files, "Add Template"); err != nil {
+		return err
+	}
+
+	slog.InfoContext(ctx, "created all template files")
 	return nil
 }
 
-func (uc *SetupRepositoryUseCase) createTemplateFiles(ctx context.Context, repo entity.Repository) error {
-	log.Printf("Creating template files for repository: %s/%s", repo.Owner, repo.Name)
+// loadTemplateSource はテンプレートリポジトリからコピーするファイルを取得する。
+// テンプレートリポジトリの owner が異なる場合は、そのリポジトリの installation で読み込む
+func (uc *SetupRepositoryUseCase) loadTemplateSource(ctx context.Context, repo entity.Repository) ([]entity.File, error) {
+	source := uc.profile.TemplateSource
+	if source == nil {
+		return nil, nil
+	}
 
-	// ワークフローファイルを最後にpushするため、順番を調整
-	files := []entity.FileContent{
-		entity.DefaultLicenseFile(),
-		entity.DefaultContributingFile(),
-		entity.DefaultSetupLabelsWorkflow(), // 最後
+	sourceRepo := entity.Repository{Owner: source.Owner, Name: source.Name, InstallationID: repo.InstallationID}
+	if !strings.EqualFold(source.Owner, repo.Owner) {
+		installationID, err := uc.githubRepo.FindRepositoryInstallation(ctx, source.Owner, source.Name)
+		if err != nil {
+			return nil, err
+		}
+		sourceRepo.InstallationID = installationID
 	}
 
-	// 各ファイルを個別に作成
-	if err := uc.githubRepo.CreateFiles(ctx, repo, files, "Add Template"); err != nil {
+	files, err := uc.githubRepo.ListDirectoryFiles(ctx, sourceRepo, source.Ref, source.Dir(), source.Matches)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template repository %s: %w", source.FullName(), err)
+	}
+
+	selected := source.Files(files)
+	slog.InfoContext(ctx, "loaded files from template repository", "template_repository", source.FullName(), "selected", len(selected), "total", len(files))
+	return selected, nil
+}
+
+// resolveConflicts は既存ファイルを確認し、各ファイルの ConflictPolicy に従って作成するファイルを決める。
+// 既に同じ内容のファイルがある場合は作成しないため、何度実行しても同じ結果になる。
+func (uc *SetupRepositoryUseCase) resolveConflicts(ctx context.Context, repo entity.Repository, files []entity.FileContent) ([]entity.FileContent, error) {
+	resolved := make([]entity.FileContent, 0, len(files))
+	for _, file := range files {
+		content, exists, err := uc.githubRepo.GetFileContent(ctx, repo, file.GetPath())
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			resolved = append(resolved, file)
+			continue
+		}
+		if content == file.GetContent() {
+			slog.InfoContext(ctx, "file is up to date, skipping", "path", file.GetPath())
+			continue
+		}
+
+		switch policy := file.GetConflictPolicy(); policy {
+		case entity.ConflictSkip:
+			slog.InfoContext(ctx, "file already exists, skipping", "path", file.GetPath())
+		case entity.ConflictOverwrite:
+			slog.InfoContext(ctx, "file already exists, overwriting", "path", file.GetPath())
+			resolved = append(resolved, file)
+		case entity.ConflictFail:
+			return nil, fmt.Errorf("file %s already exists", file.GetPath())
+		case entity.ConflictWriteAlongside:
+			alongside := entity.File{
+				Path:    entity.AlongsidePath(file.GetPath()),
+				Content: file.GetContent(),
+				Message: file.GetMessage(),
+				Mode:    file.GetMode(),
+			}
+			current, exists, err := uc.githubRepo.GetFileContent(ctx, repo, alongside.Path)
+			if err != nil {
+				return nil, err
+			}
+			if exists && current == alongside.Content {
+				slog.InfoContext(ctx, "file is up to date, skipping", "path", alongside.Path)
+				continue
+			}
+			slog.InfoContext(ctx, "file already exists, writing alongside", "path", file.GetPath(), "alongside", alongside.Path)
+			resolved = append(resolved, alongside)
+		default:
+			return nil, fmt.Errorf("unknown conflict policy %q for %s", policy, file.GetPath())
+		}
+	}
+
+	return resolved, nil
+}
+
+// SyncLabels は Labels API でリポジトリのラベルをプロファイルのラベルに揃える
+func (uc *SetupRepositoryUseCase) SyncLabels(ctx context.Context, repo entity.Repository) error {
+	plan, err := uc.PlanLabels(ctx, repo)
+	if err != nil {
 		return err
 	}
 
-	log.Printf("Created all template files")
+	slog.InfoContext(ctx, "label plan", "summary", plan.Summary())
+	for _, op := range plan.Operations {
+		slog.DebugContext(ctx, "label operation", "operation", op.String())
+	}
+
+	return uc.ApplyLabelPlan(ctx, repo, plan)
+}
+
+// PlanLabels は既存ラベルとプロファイルのラベルを比較し、必要な操作を計算する（変更はしない）
+func (uc *SetupRepositoryUseCase) PlanLabels(ctx context.Context, repo entity.Repository) (entity.LabelPlan, error) {
+	existing, err := uc.githubRepo.ListLabels(ctx, repo)
+	if err != nil {
+		return entity.LabelPlan{}, err
+	}
+
+	return entity.PlanLabels(existing, uc.profile.Labels, uc.profile.PruneLabels), nil
+}
+
+// ApplyLabelPlan は計算済みのラベル操作を順番に実行する
+func (uc *SetupRepositoryUseCase) ApplyLabelPlan(ctx context.Context, repo entity.Repository, plan entity.LabelPlan) error {
+	for _, op := range plan.Operations {
+		var err error
+		switch op.Type {
+		case entity.LabelOperationCreate:
+			err = uc.githubRepo.CreateLabel(ctx, repo, *op.Label)
+		case entity.LabelOperationUpdate, entity.LabelOperationRename:
+			err = uc.githubRepo.UpdateLabel(ctx, repo, op.Name, *op.Label)
+		case entity.LabelOperationDelete:
+			err = uc.githubRepo.DeleteLabel(ctx, repo, op.Name)
+		default:
+			err = fmt.Errorf("unknown label operation %q", op.Type)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	slog.InfoContext(ctx, "labels synced")
 	return nil
 }
 
-func (uc *SetupRepositoryUseCase) DeleteWorkflow(ctx context.Context, repo entity.Repository) error {
-	log.Printf("Deleting workflow file: %s/%s", repo.Owner, repo.Name)
+// DeleteWorkflow は setup-labels ワークフローの成功後にワークフローファイルを削除する
+func (uc *SetupRepositoryUseCase) DeleteWorkflow(ctx context.Context, repo entity.Repository) (err error) {
+	ctx, span := tracing.Start(ctx, "SetupRepositoryUseCase.DeleteWorkflow", trace.WithAttributes(
+		attribute.String("github.repository", repo.FullName()),
+	))
+	defer func() { tracing.End(span, err) }()
 
-	workflowPath := ".github/workflows/setup-labels.yml"
-	if err := uc.githubRepo.DeleteWorkflowFile(ctx, repo, workflowPath); err != nil {
+	ctx = logging.With(ctx, "repository", repo.FullName())
+	slog.InfoContext(ctx, "deleting workflow file", "path", entity.SetupLabelsWorkflowPath)
+
+	// ワークフロー完了待ちの実行記録があれば続きを記録する
+	var run *entity.SetupRun
+	if uc.runStore != nil {
+		latest, err := uc.runStore.Get(ctx, repo.Owner, repo.Name)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to load setup run", "error", err)
+		}
+		if latest != nil && latest.Status == entity.SetupRunWaiting {
+			run = latest
+			run.SetStep(entity.SetupStepLabels, entity.StepStatusSucceeded, time.Now())
+		}
+	}
+
+	deleteFile := func(ctx context.Context) error {
+		return uc.githubRepo.DeleteWorkflowFile(ctx, repo, entity.SetupLabelsWorkflowPath)
+	}
+	if run != nil {
+		if err := uc.runStep(ctx, run, entity.SetupStepCleanup, deleteFile); err != nil {
+			return err
+		}
+		run.Finish(time.Now())
+		uc.saveRun(ctx, run)
+		uc.observeRun(run)
+	} else if err := deleteFile(ctx); err != nil {
 		return err
 	}
 
-	log.Printf("Workflow file deleted successfully: %s/%s", repo.Owner, repo.Name)
+	slog.InfoContext(ctx, "workflow file deleted")
 	return nil
 }
@@ -0,0 +1,47 @@
+package usecase
+
+import (
+	"context"
+	"strings"
+
+	"github-setup-app/domain/entity"
+	"github-setup-app/domain/repository"
+)
+
+// SetupStatusFilter は実行記録一覧の絞り込み条件（空の項目は条件にしない）
+type SetupStatusFilter struct {
+	Owner  string
+	Status entity.SetupRunStatus
+}
+
+// SetupStatusUseCase はセットアップの実行記録を参照する
+type SetupStatusUseCase struct {
+	runStore repository.SetupRunStore
+}
+
+func NewSetupStatusUseCase(runStore repository.SetupRunStore) *SetupStatusUseCase {
+	return &SetupStatusUseCase{runStore: runStore}
+}
+
+func (uc *SetupStatusUseCase) Get(ctx context.Context, owner, name string) (*entity.SetupRun, error) {
+	return uc.runStore.Get(ctx, owner, name)
+}
+
+func (uc *SetupStatusUseCase) List(ctx context.Context, filter SetupStatusFilter) ([]entity.SetupRun, error) {
+	runs, err := uc.runStore.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]entity.SetupRun, 0, len(runs))
+	for _, run := range runs {
+		if filter.Owner != "" && !strings.EqualFold(run.Repository.Owner, filter.Owner) {
+			continue
+		}
+		if filter.Status != "" && run.Status != filter.Status {
+			continue
+		}
+		filtered = append(filtered, run)
+	}
+	return filtered, nil
+}